@@ -1,6 +1,13 @@
 package ci
 
-import "os"
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/testrecall/reporter/git"
+)
 
 type IVendor interface {
 	GetName() string
@@ -8,8 +15,29 @@ type IVendor interface {
 	GetBuildNumber() string
 	GetBuildURL() string
 	GetBranch() string
+	GetTag() string
+	GetBaseBranch() string
+	GetSlug() string
+
+	GetJob() string
+	GetShardIndex() string
+	GetShardTotal() string
+	GetRetryAttempt() string
+
+	GetCommitAuthor() string
+	GetCommitMessage() string
+	GetCommitTime() string
 
 	Active() bool
+	GetEnv() string
+	EnvVars() []EnvVar
+	Source(field string) string
+}
+
+// EnvVar is an environment variable a vendor reads a field from
+type EnvVar struct {
+	Field string
+	Name  string
 }
 
 type Vendor struct {
@@ -21,32 +49,162 @@ type Vendor struct {
 	BuildNumber string
 	BuildURL    string
 	PullRequest string
+	BaseBranch  string // target branch of the pull request
+
+	// HeadBranch is the source branch of a pull request, read before Branch
+	// when set, for vendors whose Branch is a merge ref on pull requests
+	HeadBranch string
+
+	// Tag is only used when it has TagPrefix, which is then stripped, for
+	// vendors that share one variable between branches and tags
+	Tag       string
+	TagPrefix string
+
+	// Slug is an owner/repo var, otherwise it is joined from SlugOwner and
+	// SlugRepo, or parsed from the clone url in RepoURL
+	Slug      string
+	SlugOwner string
+	SlugRepo  string
+	RepoURL   string
+
+	Job          string
+	ShardIndex   string
+	ShardTotal   string
+	RetryAttempt string
+
+	// offsets normalize shard indexes to 0-based and retry attempts to
+	// 1-based, as vendors disagree on where they start counting
+	ShardIndexOffset   int
+	RetryAttemptOffset int
+
+	// CommitAuthor is a "Name <email>" var, otherwise name and email are
+	// read separately
+	CommitAuthor      string
+	CommitAuthorName  string
+	CommitAuthorEmail string
+	CommitMessage     string
+	CommitTime        string
 }
 
 func (v Vendor) Active() bool           { _, found := os.LookupEnv(v.Env); return found }
 func (v Vendor) GetName() string        { return v.Name }
+func (v Vendor) GetEnv() string         { return v.Env }
 func (v Vendor) GetSHA() string         { return os.Getenv(v.SHA) }
 func (v Vendor) GetBuildNumber() string { return os.Getenv(v.BuildNumber) }
 func (v Vendor) GetBuildURL() string    { return os.Getenv(v.BuildURL) }
-func (v Vendor) GetBranch() string      { return os.Getenv(v.Branch) }
+func (v Vendor) GetBranch() string {
+	if head := os.Getenv(v.HeadBranch); head != "" {
+		return head
+	}
+	return os.Getenv(v.Branch)
+}
+func (v Vendor) GetTag() string {
+	tag := os.Getenv(v.Tag)
+	if !strings.HasPrefix(tag, v.TagPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(tag, v.TagPrefix)
+}
+func (v Vendor) GetBaseBranch() string { return os.Getenv(v.BaseBranch) }
+func (v Vendor) GetSlug() string {
+	if slug := os.Getenv(v.Slug); slug != "" {
+		return slug
+	}
+	if owner, repo := os.Getenv(v.SlugOwner), os.Getenv(v.SlugRepo); owner != "" && repo != "" {
+		return owner + "/" + repo
+	}
+	return git.SlugFromURL(os.Getenv(v.RepoURL))
+}
+func (v Vendor) GetJob() string        { return os.Getenv(v.Job) }
+func (v Vendor) GetShardIndex() string { return offsetEnv(v.ShardIndex, v.ShardIndexOffset) }
+func (v Vendor) GetShardTotal() string { return os.Getenv(v.ShardTotal) }
+func (v Vendor) GetRetryAttempt() string {
+	return offsetEnv(v.RetryAttempt, v.RetryAttemptOffset)
+}
+func (v Vendor) GetCommitAuthor() string {
+	if author := os.Getenv(v.CommitAuthor); author != "" {
+		return author
+	}
+	return author(os.Getenv(v.CommitAuthorName), os.Getenv(v.CommitAuthorEmail))
+}
+func (v Vendor) GetCommitMessage() string   { return os.Getenv(v.CommitMessage) }
+func (v Vendor) GetCommitTime() string      { return os.Getenv(v.CommitTime) }
+func (v Vendor) Source(field string) string { return firstSet(v.EnvVars(), field) }
+func (v Vendor) EnvVars() []EnvVar {
+	return envVars(map[string][]string{
+		"branch":        {v.HeadBranch, v.Branch},
+		"tag":           {v.Tag},
+		"sha":           {v.SHA},
+		"build_number":  {v.BuildNumber},
+		"build_url":     {v.BuildURL},
+		"pr":            {v.PullRequest},
+		"base_branch":   {v.BaseBranch},
+		"slug":          {v.Slug, v.SlugOwner, v.SlugRepo, v.RepoURL},
+		"job":           {v.Job},
+		"shard_index":   {v.ShardIndex},
+		"shard_total":   {v.ShardTotal},
+		"retry_attempt": {v.RetryAttempt},
+
+		"commit_author":  {v.CommitAuthor, v.CommitAuthorName, v.CommitAuthorEmail},
+		"commit_message": {v.CommitMessage},
+		"commit_time":    {v.CommitTime},
+	})
+}
 
 type Jenkins struct {
 	Name string
 	Env  string
 
 	Branch      []string
+	Tag         []string
 	SHA         []string
 	BuildNumber []string
 	BuildURL    []string
 	PullRequest []string
+	BaseBranch  []string
+	RepoURL     []string
+
+	Job []string
+
+	CommitAuthorName  []string
+	CommitAuthorEmail []string
 }
 
-func (v Jenkins) Active() bool           { _, found := os.LookupEnv(v.Env); return found }
-func (v Jenkins) GetName() string        { return v.Name }
-func (v Jenkins) GetSHA() string         { return guessEnv(v.SHA) }
-func (v Jenkins) GetBuildNumber() string { return guessEnv(v.BuildNumber) }
-func (v Jenkins) GetBuildURL() string    { return guessEnv(v.BuildURL) }
-func (v Jenkins) GetBranch() string      { return guessEnv(v.Branch) }
+func (v Jenkins) Active() bool            { _, found := os.LookupEnv(v.Env); return found }
+func (v Jenkins) GetName() string         { return v.Name }
+func (v Jenkins) GetEnv() string          { return v.Env }
+func (v Jenkins) GetSHA() string          { return guessEnv(v.SHA) }
+func (v Jenkins) GetBuildNumber() string  { return guessEnv(v.BuildNumber) }
+func (v Jenkins) GetBuildURL() string     { return guessEnv(v.BuildURL) }
+func (v Jenkins) GetBranch() string       { return guessEnv(v.Branch) }
+func (v Jenkins) GetTag() string          { return guessEnv(v.Tag) }
+func (v Jenkins) GetBaseBranch() string   { return guessEnv(v.BaseBranch) }
+func (v Jenkins) GetSlug() string         { return git.SlugFromURL(guessEnv(v.RepoURL)) }
+func (v Jenkins) GetJob() string          { return guessEnv(v.Job) }
+func (v Jenkins) GetShardIndex() string   { return "" }
+func (v Jenkins) GetShardTotal() string   { return "" }
+func (v Jenkins) GetRetryAttempt() string { return "" }
+func (v Jenkins) GetCommitAuthor() string {
+	return author(guessEnv(v.CommitAuthorName), guessEnv(v.CommitAuthorEmail))
+}
+func (v Jenkins) GetCommitMessage() string   { return "" }
+func (v Jenkins) GetCommitTime() string      { return "" }
+func (v Jenkins) Source(field string) string { return firstSet(v.EnvVars(), field) }
+func (v Jenkins) EnvVars() []EnvVar {
+	return envVars(map[string][]string{
+		"branch":       v.Branch,
+		"tag":          v.Tag,
+		"sha":          v.SHA,
+		"build_number": v.BuildNumber,
+		"build_url":    v.BuildURL,
+		"pr":           v.PullRequest,
+		"base_branch":  v.BaseBranch,
+		"slug":         v.RepoURL,
+		"job":          v.Job,
+
+		"commit_author": append(append([]string{}, v.CommitAuthorName...), v.CommitAuthorEmail...),
+	})
+}
 
 func guessEnv(envs []string) string {
 	for _, env := range envs {
@@ -57,83 +215,247 @@ func guessEnv(envs []string) string {
 	return ""
 }
 
+// envVars flattens field -> env names, sorted by field and skipping fields
+// the vendor does not provide
+func envVars(fields map[string][]string) []EnvVar {
+	vars := []EnvVar{}
+	for field, names := range fields {
+		for _, name := range names {
+			if name != "" {
+				vars = append(vars, EnvVar{Field: field, Name: name})
+			}
+		}
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return vars[i].Field < vars[j].Field })
+	return vars
+}
+
+// firstSet returns the first env var of a field that has a value, matching
+// how the getters pick between candidates
+func firstSet(vars []EnvVar, field string) string {
+	for _, v := range vars {
+		if v.Field == field && os.Getenv(v.Name) != "" {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// author formats a name and email like git does, "Name <email>"
+func author(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return name + " <" + email + ">"
+}
+
+func offsetEnv(env string, offset int) string {
+	value := os.Getenv(env)
+	if value == "" || offset == 0 {
+		return value
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return value
+	}
+	return strconv.Itoa(n + offset)
+}
+
 type Travis struct {
 	Name string
 	Env  string
 
 	Branch      string
 	BranchPR    string
+	Tag         string
 	SHA         string
 	SHAPR       string
 	BuildNumber string
 	BuildURL    string
 	PullRequest string
+	Slug        string
+
+	Job string
+
+	CommitMessage string
 }
 
 func (v Travis) Active() bool    { _, found := os.LookupEnv(v.Env); return found }
 func (v Travis) GetName() string { return v.Name }
+func (v Travis) GetEnv() string  { return v.Env }
 func (v Travis) GetSHA() string {
 	if os.Getenv(v.PullRequest) == "false" {
 		return os.Getenv(v.SHA)
 	}
 	return os.Getenv(v.SHAPR)
 }
+func (v Travis) GetTag() string         { return os.Getenv(v.Tag) }
 func (v Travis) GetBuildNumber() string { return os.Getenv(v.BuildNumber) }
-func (v Travis) GetBuildURL() string    { return os.Getenv(v.BuildURL) }
+func (v Travis) GetBaseBranch() string {
+	// on pull requests TRAVIS_BRANCH is the target branch
+	if os.Getenv(v.PullRequest) == "false" {
+		return ""
+	}
+	return os.Getenv(v.Branch)
+}
+func (v Travis) GetBuildURL() string { return os.Getenv(v.BuildURL) }
 func (v Travis) GetBranch() string {
 	if os.Getenv(v.PullRequest) == "false" {
 		return os.Getenv(v.Branch)
 	}
 	return os.Getenv(v.BranchPR)
 }
+func (v Travis) GetSlug() string          { return os.Getenv(v.Slug) }
+func (v Travis) GetJob() string           { return os.Getenv(v.Job) }
+func (v Travis) GetShardIndex() string    { return "" }
+func (v Travis) GetShardTotal() string    { return "" }
+func (v Travis) GetRetryAttempt() string  { return "" }
+func (v Travis) GetCommitAuthor() string  { return "" }
+func (v Travis) GetCommitMessage() string { return os.Getenv(v.CommitMessage) }
+func (v Travis) GetCommitTime() string    { return "" }
+func (v Travis) Source(field string) string {
+	switch {
+	case field == "branch" && os.Getenv(v.PullRequest) == "false":
+		return v.Branch
+	case field == "branch":
+		return v.BranchPR
+	case field == "sha" && os.Getenv(v.PullRequest) == "false":
+		return v.SHA
+	case field == "sha":
+		return v.SHAPR
+	case field == "base_branch" && os.Getenv(v.PullRequest) != "false":
+		return v.Branch
+	case field == "base_branch":
+		return ""
+	}
+	return firstSet(v.EnvVars(), field)
+}
+func (v Travis) EnvVars() []EnvVar {
+	return envVars(map[string][]string{
+		"branch":       {v.Branch, v.BranchPR},
+		"tag":          {v.Tag},
+		"sha":          {v.SHA, v.SHAPR},
+		"build_number": {v.BuildNumber},
+		"build_url":    {v.BuildURL},
+		"pr":           {v.PullRequest},
+		"slug":         {v.Slug},
+		"job":          {v.Job},
+
+		"commit_message": {v.CommitMessage},
+	})
+}
 
 var vendors = []IVendor{
 	Vendor{
 		Name:        "CircleCI",
 		Env:         "CIRCLECI", // true if circle
 		Branch:      "CIRCLE_BRANCH",
+		Tag:         "CIRCLE_TAG",
 		SHA:         "CIRCLE_SHA1",
 		BuildNumber: "CIRCLE_BUILD_NUM",
 		BuildURL:    "CIRCLE_BUILD_URL",
 		PullRequest: "CIRCLE_PULL_REQUEST", // url of pull request
+		SlugOwner:   "CIRCLE_PROJECT_USERNAME",
+		SlugRepo:    "CIRCLE_PROJECT_REPONAME",
+
+		Job:        "CIRCLE_JOB",
+		ShardIndex: "CIRCLE_NODE_INDEX", // 0-based
+		ShardTotal: "CIRCLE_NODE_TOTAL",
 	},
 	Vendor{
 		Name:        "Gitlab",
 		Env:         "GITLAB_CI",
 		Branch:      "CI_COMMIT_REF_NAME", // CI_BUILD_REF_NAME
-		SHA:         "CI_COMMIT_SHA",      // CI_BUILD_REF
-		BuildNumber: "CI_JOB_ID",          // CI_BUILD_ID
+		Tag:         "CI_COMMIT_TAG",
+		SHA:         "CI_COMMIT_SHA", // CI_BUILD_REF
+		BuildNumber: "CI_JOB_ID",     // CI_BUILD_ID
 		BuildURL:    "CI_JOB_URL",
 		PullRequest: "CI_COMMIT_BEFORE_SHA", // url of pull request
+		BaseBranch:  "CI_MERGE_REQUEST_TARGET_BRANCH_NAME",
+		Slug:        "CI_PROJECT_PATH", // group/subgroup/project
+
+		Job:              "CI_JOB_NAME",
+		ShardIndex:       "CI_NODE_INDEX", // 1-based
+		ShardTotal:       "CI_NODE_TOTAL",
+		ShardIndexOffset: -1,
+
+		CommitAuthor:  "CI_COMMIT_AUTHOR", // Name <email>
+		CommitMessage: "CI_COMMIT_MESSAGE",
+		CommitTime:    "CI_COMMIT_TIMESTAMP",
 	},
 	Vendor{
 		Name:        "GithubAtions",
 		Env:         "GITHUB_ACTIONS",
-		Branch:      "GITHUB_REF",        // CI_BUILD_REF_NAME
+		Branch:      "GITHUB_REF_NAME", // branch or tag, <n>/merge on pull requests
+		HeadBranch:  "GITHUB_HEAD_REF", // only set on pull requests
+		Tag:         "GITHUB_REF",      // refs/tags/<tag> on tag pushes
+		TagPrefix:   "refs/tags/",
 		SHA:         "GITHUB_SHA",        // CI_BUILD_REF
 		BuildNumber: "GITHUB_RUN_NUMBER", // CI_BUILD_ID
 		BuildURL:    "GITHUB_API_URL",
 		PullRequest: "", // url of pull request
+		BaseBranch:  "GITHUB_BASE_REF",
+		Slug:        "GITHUB_REPOSITORY",
+
+		Job:          "GITHUB_JOB",
+		RetryAttempt: "GITHUB_RUN_ATTEMPT", // 1-based
+	},
+	Vendor{
+		Name:        "Buildkite",
+		Env:         "BUILDKITE",
+		Branch:      "BUILDKITE_BRANCH",
+		Tag:         "BUILDKITE_TAG",
+		SHA:         "BUILDKITE_COMMIT",
+		BuildNumber: "BUILDKITE_BUILD_NUMBER",
+		BuildURL:    "BUILDKITE_BUILD_URL",
+		PullRequest: "BUILDKITE_PULL_REQUEST", // PR number, or 'false'
+		BaseBranch:  "BUILDKITE_PULL_REQUEST_BASE_BRANCH",
+		RepoURL:     "BUILDKITE_REPO", // clone url
+
+		Job:                "BUILDKITE_LABEL",
+		ShardIndex:         "BUILDKITE_PARALLEL_JOB", // 0-based
+		ShardTotal:         "BUILDKITE_PARALLEL_JOB_COUNT",
+		RetryAttempt:       "BUILDKITE_RETRY_COUNT", // 0 on the first run
+		RetryAttemptOffset: 1,
+
+		CommitAuthorName:  "BUILDKITE_BUILD_AUTHOR",
+		CommitAuthorEmail: "BUILDKITE_BUILD_AUTHOR_EMAIL",
+		CommitMessage:     "BUILDKITE_MESSAGE",
 	},
 	Jenkins{
 		Name:        "Jenkins",
 		Env:         "JENKINS_URL",
 		Branch:      []string{"ghprbSourceBranch", "BRANCH_NAME"}, // CI_BUILD_REF_NAME
+		Tag:         []string{"TAG_NAME"},                         // multibranch tag builds
 		SHA:         []string{"ghprbActualCommit", "GIT_COMMIT"},  // CI_BUILD_REF
 		BuildNumber: []string{"ghprbPullId", "BUILD_NUMBER"},      // CI_BUILD_ID
 		BuildURL:    []string{"ghprbPullLink", "BUILD_URL"},
 		PullRequest: []string{"ghprbPullId"}, // url of pull request
+		BaseBranch:  []string{"ghprbTargetBranch", "CHANGE_TARGET"},
+		RepoURL:     []string{"GIT_URL"}, // clone url
+
+		Job: []string{"JOB_NAME"},
+
+		CommitAuthorName:  []string{"ghprbActualCommitAuthor"},
+		CommitAuthorEmail: []string{"ghprbActualCommitAuthorEmail"},
 	},
 	Travis{
 		Name:        "TravisCI",
 		Env:         "TRAVIS",
 		Branch:      "TRAVIS_BRANCH",
 		BranchPR:    "TRAVIS_PULL_REQUEST_BRANCH",
+		Tag:         "TRAVIS_TAG",
 		SHA:         "TRAVIS_COMMIT",
 		SHAPR:       "TRAVIS_PULL_REQUEST_SHA",
 		BuildNumber: "TRAVIS_BUILD_NUMBER",
 		BuildURL:    "TRAVIS_BUILD_WEB_URL",
 		PullRequest: "TRAVIS_PULL_REQUEST", // PR number, or 'false'
+		Slug:        "TRAVIS_REPO_SLUG",
+
+		Job: "TRAVIS_JOB_NAME",
+
+		CommitMessage: "TRAVIS_COMMIT_MESSAGE",
 	},
 }
 
@@ -145,3 +467,8 @@ func GetVendor() (IVendor, bool) {
 	}
 	return Vendor{}, false
 }
+
+// Vendors lists every supported vendor, in detection order
+func Vendors() []IVendor {
+	return vendors
+}
@@ -23,6 +23,9 @@ func TestCircleCIBranch(t *testing.T) {
 	setEnv(t, "CIRCLE_BUILD_NUM", "18")
 	setEnv(t, "CIRCLE_BUILD_URL", "https://circleci.com/gh/KlotzAndrew/tre/18")
 	// setEnv(t, "CIRCLE_PULL_REQUEST", "") not set
+	setEnv(t, "CIRCLE_JOB", "test")
+	setEnv(t, "CIRCLE_NODE_INDEX", "0")
+	setEnv(t, "CIRCLE_NODE_TOTAL", "4")
 
 	vendor, found := ci.GetVendor()
 	assert.True(t, found)
@@ -31,6 +34,10 @@ func TestCircleCIBranch(t *testing.T) {
 	assert.Equal(t, "18", vendor.GetBuildNumber())
 	assert.Equal(t, "https://circleci.com/gh/KlotzAndrew/tre/18", vendor.GetBuildURL())
 	assert.Equal(t, "master", vendor.GetBranch())
+	assert.Equal(t, "test", vendor.GetJob())
+	assert.Equal(t, "0", vendor.GetShardIndex())
+	assert.Equal(t, "4", vendor.GetShardTotal())
+	assert.Equal(t, "", vendor.GetRetryAttempt())
 }
 
 func TestCircleCIPR(t *testing.T) {
@@ -71,6 +78,12 @@ func TestGitlabBranch(t *testing.T) {
 	setEnv(t, "CI_JOB_ID", buildNumber)
 	setEnv(t, "CI_JOB_URL", buildURL)
 	// setEnv(t, "CIRCLE_PULL_REQUEST", "") not set
+	setEnv(t, "CI_JOB_NAME", "rspec 2/3")
+	setEnv(t, "CI_NODE_INDEX", "2")
+	setEnv(t, "CI_NODE_TOTAL", "3")
+	setEnv(t, "CI_COMMIT_AUTHOR", "Jane Doe <jane@example.com>")
+	setEnv(t, "CI_COMMIT_MESSAGE", "Fix flaky spec\n\nDetails")
+	setEnv(t, "CI_COMMIT_TIMESTAMP", "2026-10-19T10:00:00+00:00")
 
 	vendor, found := ci.GetVendor()
 	assert.True(t, found)
@@ -79,10 +92,25 @@ func TestGitlabBranch(t *testing.T) {
 	assert.Equal(t, buildNumber, vendor.GetBuildNumber())
 	assert.Equal(t, buildURL, vendor.GetBuildURL())
 	assert.Equal(t, branch, vendor.GetBranch())
+	assert.Equal(t, "rspec 2/3", vendor.GetJob())
+	assert.Equal(t, "1", vendor.GetShardIndex())
+	assert.Equal(t, "3", vendor.GetShardTotal())
+	assert.Equal(t, "Jane Doe <jane@example.com>", vendor.GetCommitAuthor())
+	assert.Equal(t, "Fix flaky spec\n\nDetails", vendor.GetCommitMessage())
+	assert.Equal(t, "2026-10-19T10:00:00+00:00", vendor.GetCommitTime())
 }
 
 func TestGitlabMR(t *testing.T) {
-	t.Skip()
+	os.Clearenv()
+	setEnv(t, "GITLAB_CI", "true")
+	setEnv(t, "CI_COMMIT_REF_NAME", "feature")
+	setEnv(t, "CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "main")
+
+	vendor, found := ci.GetVendor()
+	assert.True(t, found)
+	assert.Equal(t, "feature", vendor.GetBranch())
+	assert.Equal(t, "main", vendor.GetBaseBranch())
+	assert.Equal(t, "CI_MERGE_REQUEST_TARGET_BRANCH_NAME", vendor.Source("base_branch"))
 }
 
 func TestTravisBranch(t *testing.T) {
@@ -101,6 +129,7 @@ func TestTravisBranch(t *testing.T) {
 	assert.Equal(t, "30", vendor.GetBuildNumber())
 	assert.Equal(t, "https://travis-ci.com/KlotzAndrew/tre/builds/149181042", vendor.GetBuildURL())
 	assert.Equal(t, "master", vendor.GetBranch())
+	assert.Equal(t, "", vendor.GetBaseBranch())
 }
 
 func TestTravisPR(t *testing.T) {
@@ -127,14 +156,87 @@ func TestTravisPR(t *testing.T) {
 	assert.Equal(t, buildNumber, vendor.GetBuildNumber())
 	assert.Equal(t, buildURL, vendor.GetBuildURL())
 	assert.Equal(t, branch, vendor.GetBranch())
+	assert.Equal(t, "master", vendor.GetBaseBranch())
+	assert.Equal(t, "TRAVIS_BRANCH", vendor.Source("base_branch"))
 }
 
 func TestGitlab(t *testing.T) {
 	t.Skip("tbd")
 }
 
-func TestGithubAtions(t *testing.T) {
-	t.Skip("tbd")
+func TestGithubAtionsBranch(t *testing.T) {
+	for _, tt := range []struct {
+		env    map[string]string
+		want   string
+		source string
+	}{
+		{map[string]string{"GITHUB_REF": "refs/heads/main", "GITHUB_REF_NAME": "main"}, "main", "GITHUB_REF_NAME"},
+		{map[string]string{"GITHUB_REF": "refs/tags/v1.2", "GITHUB_REF_NAME": "v1.2"}, "v1.2", "GITHUB_REF_NAME"},
+		{map[string]string{"GITHUB_REF": "refs/pull/7/merge", "GITHUB_REF_NAME": "7/merge", "GITHUB_HEAD_REF": "feature"}, "feature", "GITHUB_HEAD_REF"},
+	} {
+		os.Clearenv()
+		setEnv(t, "GITHUB_ACTIONS", "true")
+		for k, v := range tt.env {
+			setEnv(t, k, v)
+		}
+
+		vendor, found := ci.GetVendor()
+		assert.True(t, found)
+		assert.Equal(t, tt.want, vendor.GetBranch(), tt.env)
+		assert.Equal(t, tt.source, vendor.Source("branch"), tt.env)
+	}
+}
+
+func TestGithubAtionsRetry(t *testing.T) {
+	os.Clearenv()
+	setEnv(t, "GITHUB_ACTIONS", "true")
+	setEnv(t, "GITHUB_JOB", "test")
+	setEnv(t, "GITHUB_RUN_ATTEMPT", "2")
+
+	vendor, found := ci.GetVendor()
+	assert.True(t, found)
+	assert.Equal(t, "GithubAtions", vendor.GetName())
+	assert.Equal(t, "test", vendor.GetJob())
+	assert.Equal(t, "2", vendor.GetRetryAttempt())
+	assert.Equal(t, "", vendor.GetShardIndex())
+	assert.Equal(t, "", vendor.GetShardTotal())
+}
+
+func TestBuildkiteParallel(t *testing.T) {
+	const name = "Buildkite"
+	const branch = "main"
+	const sha = "fcff3fa2c0ef8f1c6e113ce8c338681cdeb48f85"
+	const buildNumber = "412"
+	const buildURL = "https://buildkite.com/acme/app/builds/412"
+
+	os.Clearenv()
+	setEnv(t, "BUILDKITE", "true")
+	setEnv(t, "BUILDKITE_BRANCH", branch)
+	setEnv(t, "BUILDKITE_COMMIT", sha)
+	setEnv(t, "BUILDKITE_BUILD_NUMBER", buildNumber)
+	setEnv(t, "BUILDKITE_BUILD_URL", buildURL)
+	setEnv(t, "BUILDKITE_LABEL", ":rspec: specs")
+	setEnv(t, "BUILDKITE_PARALLEL_JOB", "3")
+	setEnv(t, "BUILDKITE_PARALLEL_JOB_COUNT", "8")
+	setEnv(t, "BUILDKITE_RETRY_COUNT", "0")
+	setEnv(t, "BUILDKITE_BUILD_AUTHOR", "Jane Doe")
+	setEnv(t, "BUILDKITE_BUILD_AUTHOR_EMAIL", "jane@example.com")
+	setEnv(t, "BUILDKITE_MESSAGE", "Fix flaky spec")
+
+	vendor, found := ci.GetVendor()
+	assert.True(t, found)
+	assert.Equal(t, name, vendor.GetName())
+	assert.Equal(t, sha, vendor.GetSHA())
+	assert.Equal(t, buildNumber, vendor.GetBuildNumber())
+	assert.Equal(t, buildURL, vendor.GetBuildURL())
+	assert.Equal(t, branch, vendor.GetBranch())
+	assert.Equal(t, ":rspec: specs", vendor.GetJob())
+	assert.Equal(t, "3", vendor.GetShardIndex())
+	assert.Equal(t, "8", vendor.GetShardTotal())
+	assert.Equal(t, "1", vendor.GetRetryAttempt())
+	assert.Equal(t, "Jane Doe <jane@example.com>", vendor.GetCommitAuthor())
+	assert.Equal(t, "Fix flaky spec", vendor.GetCommitMessage())
+	assert.Equal(t, "BUILDKITE_BUILD_AUTHOR", vendor.Source("commit_author"))
 }
 
 // https://github.com/jenkinsci/ghprb-plugin
@@ -175,6 +277,7 @@ func TestJenkinsBranch(t *testing.T) {
 	setEnv(t, "GIT_COMMIT", sha)
 	setEnv(t, "BUILD_NUMBER", buildNumber)
 	setEnv(t, "BUILD_URL", buildURL)
+	setEnv(t, "JOB_NAME", "tre/main")
 
 	vendor, found := ci.GetVendor()
 	assert.True(t, found)
@@ -183,8 +286,84 @@ func TestJenkinsBranch(t *testing.T) {
 	assert.Equal(t, buildNumber, vendor.GetBuildNumber())
 	assert.Equal(t, buildURL, vendor.GetBuildURL())
 	assert.Equal(t, branch, vendor.GetBranch())
+	assert.Equal(t, "tre/main", vendor.GetJob())
+	assert.Equal(t, "", vendor.GetShardIndex())
 }
 
 func setEnv(t *testing.T, key, value string) {
 	assert.NoError(t, os.Setenv(key, value))
 }
+
+func TestVendorEnvVars(t *testing.T) {
+	os.Clearenv()
+	setEnv(t, "TRAVIS", "true")
+
+	vendor, found := ci.GetVendor()
+	assert.True(t, found)
+	assert.Equal(t, "TRAVIS", vendor.GetEnv())
+	assert.Equal(t, []ci.EnvVar{
+		{Field: "branch", Name: "TRAVIS_BRANCH"},
+		{Field: "branch", Name: "TRAVIS_PULL_REQUEST_BRANCH"},
+		{Field: "build_number", Name: "TRAVIS_BUILD_NUMBER"},
+		{Field: "build_url", Name: "TRAVIS_BUILD_WEB_URL"},
+		{Field: "commit_message", Name: "TRAVIS_COMMIT_MESSAGE"},
+		{Field: "job", Name: "TRAVIS_JOB_NAME"},
+		{Field: "pr", Name: "TRAVIS_PULL_REQUEST"},
+		{Field: "sha", Name: "TRAVIS_COMMIT"},
+		{Field: "sha", Name: "TRAVIS_PULL_REQUEST_SHA"},
+		{Field: "slug", Name: "TRAVIS_REPO_SLUG"},
+		{Field: "tag", Name: "TRAVIS_TAG"},
+	}, vendor.EnvVars())
+}
+
+func TestVendorTags(t *testing.T) {
+	for _, tt := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"CIRCLECI": "true", "CIRCLE_TAG": "v1.2.0"}, "v1.2.0"},
+		{map[string]string{"GITLAB_CI": "true", "CI_COMMIT_TAG": "v1.2.0"}, "v1.2.0"},
+		{map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REF": "refs/tags/v1.2.0"}, "v1.2.0"},
+		{map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REF": "refs/heads/main"}, ""},
+		{map[string]string{"BUILDKITE": "true", "BUILDKITE_TAG": "v1.2.0"}, "v1.2.0"},
+		{map[string]string{"JENKINS_URL": "https://jenkins.io", "TAG_NAME": "v1.2.0"}, "v1.2.0"},
+		{map[string]string{"TRAVIS": "true", "TRAVIS_TAG": "v1.2.0"}, "v1.2.0"},
+		{map[string]string{"TRAVIS": "true"}, ""},
+	} {
+		os.Clearenv()
+		for k, v := range tt.env {
+			setEnv(t, k, v)
+		}
+
+		vendor, found := ci.GetVendor()
+		assert.True(t, found)
+		assert.Equal(t, tt.want, vendor.GetTag(), tt.env)
+	}
+}
+
+func TestVendorSlugs(t *testing.T) {
+	for _, tt := range []struct {
+		env    map[string]string
+		want   string
+		source string
+	}{
+		{map[string]string{"CIRCLECI": "true", "CIRCLE_PROJECT_USERNAME": "TestRecall", "CIRCLE_PROJECT_REPONAME": "reporter"}, "TestRecall/reporter", "CIRCLE_PROJECT_USERNAME"},
+		{map[string]string{"CIRCLECI": "true", "CIRCLE_PROJECT_REPONAME": "reporter"}, "", "CIRCLE_PROJECT_REPONAME"},
+		{map[string]string{"GITLAB_CI": "true", "CI_PROJECT_PATH": "group/subgroup/project"}, "group/subgroup/project", "CI_PROJECT_PATH"},
+		{map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REPOSITORY": "TestRecall/reporter"}, "TestRecall/reporter", "GITHUB_REPOSITORY"},
+		{map[string]string{"BUILDKITE": "true", "BUILDKITE_REPO": "git@github.com:TestRecall/reporter.git"}, "TestRecall/reporter", "BUILDKITE_REPO"},
+		{map[string]string{"JENKINS_URL": "https://jenkins.io", "GIT_URL": "https://gitlab.example.com/group/sub/project.git"}, "group/sub/project", "GIT_URL"},
+		{map[string]string{"TRAVIS": "true", "TRAVIS_REPO_SLUG": "TestRecall/reporter"}, "TestRecall/reporter", "TRAVIS_REPO_SLUG"},
+		{map[string]string{"TRAVIS": "true"}, "", ""},
+	} {
+		os.Clearenv()
+		for k, v := range tt.env {
+			setEnv(t, k, v)
+		}
+
+		vendor, found := ci.GetVendor()
+		assert.True(t, found)
+		assert.Equal(t, tt.want, vendor.GetSlug(), tt.env)
+		assert.Equal(t, tt.source, vendor.Source("slug"), tt.env)
+	}
+}
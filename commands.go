@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/testrecall/reporter/reporter"
+)
+
+func runUpload(o *options, args []string) {
+	policy, err := o.policy()
+	if err != nil {
+		o.fatal(err)
+	}
+	payloads, err := o.payloads()
+	if errors.Is(err, reporter.ErrNoReports) {
+		o.logger.Warn(err)
+	} else if err != nil {
+		o.fatal(err)
+	}
+	results, _ := o.upload(payloads)
+	os.Exit(o.exitCode(policy, results))
+}
+
+// sentRun is a payload as upload prepared it, and the url of the run it
+// created, empty when it was not sent
+type sentRun struct {
+	payload reporter.RequestPayload
+	url     string
+}
+
+// upload sends every payload and tallies the results the exit policy looks
+// at, returning the runs of the payloads that had reports. A payload that
+// cannot be sent counts as a failed upload, so it cannot hide the test
+// results. One that cannot be prepared fails the setup, which -softFail
+// does not ignore, and one without reports counts as no tests.
+func (o *options) upload(payloads []reporter.RequestPayload) (reporter.Results, []sentRun) {
+	results := reporter.Results{}
+	runs := []sentRun{}
+	sender := reporter.NewSender(o.logger)
+	if o.spoolDir != "" {
+		o.sendSpooled()
+	}
+	history := o.loadHistory()
+
+	failed, spooled := 0, 0
+	for _, payload := range payloads {
+		if err := payload.GetRunData(); errors.Is(err, reporter.ErrNoReports) {
+			o.logger.Warn(err)
+			continue
+		} else if err != nil {
+			o.logger.Error(err)
+			results.SetupFailed = true
+			continue
+		}
+		results.Add(payload.Results())
+
+		url, err := "", o.prepare(&payload, history)
+		prepared := err == nil
+		if prepared {
+			url, err = sender.SendRun(o.endpoint, payload)
+		} else {
+			o.logger.Error(err)
+			results.SetupFailed = true
+		}
+		runs = append(runs, sentRun{payload: payload, url: url})
+		// watch writes one summary for all its parts when it finishes
+		if payload.RequestData.Part == nil {
+			o.writeJobSummary(payload, url)
+		}
+		if !prepared {
+			continue
+		}
+		if err == nil {
+			o.logger.Debug("upload success!")
+			continue
+		}
+
+		o.logger.Debug("upload failed!")
+		o.uploadError(err)
+		failed++
+		if o.spoolDir != "" {
+			path, err := reporter.Spool(o.spoolDir, payload)
+			if err != nil {
+				o.uploadError(err)
+				continue
+			}
+			o.logger.Debugf("spooled the run to %s", path)
+			spooled++
+		}
+	}
+
+	if history != nil {
+		if err := history.Save(o.history); err != nil {
+			o.logger.Warnf("unable to save the history: %v", err)
+		}
+	}
+
+	if len(results.Flaky) > 0 {
+		o.logger.Infof("%d tests passed on a retry and are labelled flaky", len(results.Flaky))
+	}
+	if failed > 0 {
+		results.UploadFailed = true
+		o.uploadError(uploadSummary(failed, len(payloads), spooled, o.spoolDir, o.softFail))
+	}
+	return results, runs
+}
+
+// prepare resolves the metadata of a payload and labels its tests, from the
+// history and the retries in its reports
+func (o *options) prepare(payload *reporter.RequestPayload, history *reporter.History) error {
+	if err := payload.Setup(); err != nil {
+		return err
+	}
+	if history != nil {
+		payload.RequestData.Labels = history.Labels(*payload)
+		history.Record(*payload)
+		logLabels(o.logger, payload.RequestData.Labels)
+	}
+	payload.LabelRetries()
+	return nil
+}
+
+// logLabels counts the failed tests by their history label
+func logLabels(logger *logrus.Logger, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	counts := map[string]int{}
+	for _, label := range labels {
+		counts[label]++
+	}
+	logger.Infof("%d tests failed: %d new, %d persistent, %d flaky", len(labels),
+		counts[reporter.LabelNew], counts[reporter.LabelPersistent], counts[reporter.LabelFlaky])
+}
+
+// writeJobSummary appends a markdown summary of the payload's reports to
+// -jobSummary, or to the job summary of GitHub Actions, linking to the run
+// at runURL
+func (o *options) writeJobSummary(payload reporter.RequestPayload, runURL string) {
+	path := o.jobSummary
+	if path == "" {
+		path = os.Getenv("GITHUB_STEP_SUMMARY")
+	}
+	if path == "" {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		o.logger.Warnf("unable to write the job summary: %v", err)
+		return
+	}
+	defer f.Close()
+
+	payload.Markdown(f, runURL)
+	o.logger.Debugf("wrote the job summary to %s", path)
+}
+
+// sendSpooled retries the runs earlier uploads left in -spoolDir
+func (o *options) sendSpooled() {
+	sent, err := reporter.NewSender(o.logger).SendSpooled(o.endpoint, o.spoolDir)
+	if sent > 0 {
+		o.logger.Infof("sent %d spooled runs from %s", sent, o.spoolDir)
+	}
+	if err != nil {
+		o.logger.Warnf("unable to send spooled runs from %s: %v", o.spoolDir, err)
+	}
+}
+
+// uploadError logs an upload error, as a warning with -softFail
+func (o *options) uploadError(err any) {
+	if o.softFail {
+		o.logger.Warn(err)
+		return
+	}
+	o.logger.Error(err)
+}
+
+func uploadSummary(failed, total, spooled int, spoolDir string, softFail bool) string {
+	summary := fmt.Sprintf("%d of %d uploads failed", failed, total)
+	if spooled > 0 {
+		summary += fmt.Sprintf(", %d spooled to %s for the next run", spooled, spoolDir)
+	}
+	if softFail {
+		summary += ", ignored by -softFail"
+	}
+	return summary
+}
+
+// policy builds the exit policy from the flags
+func (o *options) policy() (reporter.Policy, error) {
+	failOn, err := reporter.ParseFailOn(o.failOn)
+	if err != nil {
+		return reporter.Policy{}, err
+	}
+	// -setExitCode=false predates -failOn, and still failed on uploads
+	if _, set := o.sources["failOn"]; !set && !shouldExitOnFail(o.setExitCode) {
+		failOn = []string{reporter.FailOnUpload}
+	}
+
+	if o.softFail {
+		failOn = withoutReason(failOn, reporter.FailOnUpload)
+	}
+
+	policy := reporter.Policy{FailOn: failOn, MaxFailures: o.maxFailures}
+	if o.baseline != "" {
+		if policy.Baseline, err = reporter.LoadBaseline(o.baseline); err != nil {
+			return reporter.Policy{}, err
+		}
+	} else if policy.FailsOn(reporter.FailOnNewFailures) {
+		return reporter.Policy{}, errors.New("-failOn new-failures needs a -baseline of known failures")
+	}
+	if o.quarantine != "" {
+		quarantine, err := reporter.LoadQuarantine(o.quarantine)
+		if err != nil {
+			return reporter.Policy{}, err
+		}
+		active, expired := quarantine.Active(time.Now())
+		for _, entry := range expired {
+			o.logger.Warnf("quarantine of %s, owned by %s, expired on %s, its failures fail the run again", entry.Test, entry.Owner, entry.Expires)
+		}
+		policy.Quarantine = active
+	}
+	return policy, nil
+}
+
+func withoutReason(reasons []string, reason string) []string {
+	kept := []string{}
+	for _, r := range reasons {
+		if r != reason {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// exitCode applies the exit policy to the results, logging why the run
+// fails
+func (o *options) exitCode(policy reporter.Policy, results reporter.Results) int {
+	if quarantined := policy.Quarantine.Quarantined(results); len(quarantined) > 0 {
+		o.logger.Infof("%d quarantined tests failed, which does not fail the run: %s", len(quarantined), strings.Join(quarantined, ", "))
+	}
+	code, why := policy.ExitCode(results)
+	if code != reporter.ExitOK {
+		o.logger.Infof("exiting with %d: %s", code, why)
+	}
+	return code
+}
+
+func runValidate(o *options, args []string) {
+	reports := o.reports()
+	if !reports.Validate(os.Stdout) {
+		os.Exit(reporter.ExitInvalid)
+	}
+}
+
+func runSummary(o *options, args []string) {
+	reports := o.reports()
+	if history := o.loadHistory(); history != nil {
+		reports.RequestData.Labels = history.Labels(reports)
+	}
+	reports.Summary(os.Stdout)
+}
+
+func runConvert(o *options, args []string) {
+	reports := o.reports()
+
+	if o.output == "-" {
+		if err := reports.Convert(os.Stdout, o.format); err != nil {
+			o.fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Create(o.output)
+	if err != nil {
+		o.fatal(err)
+	}
+	if err := reports.Convert(f, o.format); err != nil {
+		f.Close()
+		o.fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		o.fatal(err)
+	}
+}
+
+func runDoctor(o *options, args []string) {
+	payload, err := o.payload()
+	if err != nil {
+		o.fatal(err)
+	}
+	payload.Doctor(os.Stdout, o.endpoint, o.config.Projects...)
+}
+
+func runConfig(o *options, args []string) {
+	if len(args) != 1 || args[0] != "show" {
+		o.flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := o.config.Show(os.Stdout, o.flags, o.sources); err != nil {
+		o.fatal(err)
+	}
+}
+
+func shouldExitOnFail(s string) bool {
+	if s == "false" || s == "f" {
+		return false
+	}
+	return true
+}
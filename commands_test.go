@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/config"
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.txt")
+	require.NoError(t, os.WriteFile(baseline, []byte("# known\nsuite/flaky\n"), 0644))
+	quarantine := filepath.Join(dir, "quarantine.yml")
+	require.NoError(t, os.WriteFile(quarantine, []byte(`
+- test: suite/active
+  owner: dev
+  expires: 2999-01-01
+- test: suite/expired
+  owner: dev
+  expires: 2000-01-01
+`), 0644))
+
+	defaultFailOn := strings.Join(reporter.DefaultFailOn, ",")
+	setFailOn := map[string]config.Source{"failOn": {Kind: config.SourceFlag, Name: "-failOn"}}
+	for _, tt := range []struct {
+		name        string
+		o           options
+		failOn      []string
+		baseline    map[string]bool
+		quarantined []string
+		err         string
+	}{
+		{name: "default", o: options{failOn: defaultFailOn}, failOn: reporter.DefaultFailOn},
+		{name: "setExitCode=false", o: options{failOn: defaultFailOn, setExitCode: "false"}, failOn: []string{reporter.FailOnUpload}},
+		{name: "setExitCode=f", o: options{failOn: defaultFailOn, setExitCode: "f"}, failOn: []string{reporter.FailOnUpload}},
+		{name: "failOn wins over setExitCode", o: options{failOn: "failures", setExitCode: "false", sources: setFailOn}, failOn: []string{reporter.FailOnFailures}},
+		{name: "softFail", o: options{failOn: defaultFailOn, softFail: true}, failOn: []string{reporter.FailOnFailures, reporter.FailOnInvalid, reporter.FailOnNoTests}},
+		{name: "softFail and setExitCode=false", o: options{failOn: defaultFailOn, setExitCode: "false", softFail: true}, failOn: []string{}},
+		{name: "baseline", o: options{failOn: "new-failures", baseline: baseline}, failOn: []string{reporter.FailOnNewFailures}, baseline: map[string]bool{"suite/flaky": true}},
+		{name: "quarantine", o: options{failOn: "failures", quarantine: quarantine}, failOn: []string{reporter.FailOnFailures}, quarantined: []string{"suite/active"}},
+		{name: "unknown reason", o: options{failOn: "failures,typo"}, err: "typo"},
+		{name: "new-failures without baseline", o: options{failOn: "new-failures"}, err: "needs a -baseline"},
+		{name: "missing baseline", o: options{failOn: "failures", baseline: filepath.Join(dir, "missing.txt")}, err: "missing.txt"},
+		{name: "missing quarantine", o: options{failOn: "failures", quarantine: filepath.Join(dir, "missing.yml")}, err: "missing.yml"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.o.logger = logrus.New()
+			policy, err := tt.o.policy()
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.failOn, policy.FailOn)
+			assert.Equal(t, tt.baseline, policy.Baseline)
+
+			quarantined := []string{}
+			for _, entry := range policy.Quarantine {
+				quarantined = append(quarantined, entry.Test)
+			}
+			assert.ElementsMatch(t, tt.quarantined, quarantined)
+		})
+	}
+}
+
+func TestWithoutReason(t *testing.T) {
+	for _, tt := range []struct {
+		reasons []string
+		want    []string
+	}{
+		{nil, []string{}},
+		{[]string{"upload"}, []string{}},
+		{[]string{"failures", "invalid"}, []string{"failures", "invalid"}},
+		{[]string{"failures", "upload", "invalid", "upload"}, []string{"failures", "invalid"}},
+	} {
+		assert.Equal(t, tt.want, withoutReason(tt.reasons, reporter.FailOnUpload), tt.reasons)
+	}
+}
+
+func TestUploadSummary(t *testing.T) {
+	for _, tt := range []struct {
+		failed, total, spooled int
+		softFail               bool
+		want                   string
+	}{
+		{1, 1, 0, false, "1 of 1 uploads failed"},
+		{1, 3, 1, false, "1 of 3 uploads failed, 1 spooled to spool for the next run"},
+		{2, 2, 0, true, "2 of 2 uploads failed, ignored by -softFail"},
+		{2, 2, 2, true, "2 of 2 uploads failed, 2 spooled to spool for the next run, ignored by -softFail"},
+	} {
+		assert.Equal(t, tt.want, uploadSummary(tt.failed, tt.total, tt.spooled, "spool", tt.softFail))
+	}
+}
@@ -0,0 +1,124 @@
+// Package config loads the repository level .testrecall.yml file, and
+// records where each setting was resolved from.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultPath = ".testrecall.yml"
+
+// Config defaults the command line flags for every upload, each setting
+// naming the flag it stands in for. Values are kept as strings and parsed by
+// the flag itself, so the file accepts exactly what the command line does.
+type Config struct {
+	File        Patterns `yaml:"file" flag:"file"`
+	Exclude     []string `yaml:"exclude" flag:"exclude"`
+	AllDefaults string   `yaml:"all_defaults" flag:"allDefaults"`
+	MaxAge      string   `yaml:"max_age" flag:"maxAge"`
+	NewerThan   string   `yaml:"newer_than" flag:"newerThan"`
+	History     string   `yaml:"history" flag:"history"`
+
+	Host     string `yaml:"host" flag:"host"`
+	Branch   string `yaml:"branch" flag:"branch"`
+	SHA      string `yaml:"sha" flag:"sha"`
+	Tag      string `yaml:"tag" flag:"tag"`
+	Describe string `yaml:"describe" flag:"describe"`
+
+	ChangedFiles    string `yaml:"changed_files" flag:"changedFiles"`
+	BaseBranch      string `yaml:"base_branch" flag:"baseBranch"`
+	MaxChangedFiles string `yaml:"max_changed_files" flag:"maxChangedFiles"`
+	PR              string `yaml:"pr" flag:"pr"`
+
+	Slug        string `yaml:"slug" flag:"slug"`
+	CIName      string `yaml:"ci_name" flag:"ciName"`
+	BuildNumber string `yaml:"build_number" flag:"buildnumber"`
+	BuildURL    string `yaml:"build_url" flag:"buildurl"`
+	Job         string `yaml:"job" flag:"job"`
+
+	ShardIndex   string `yaml:"shard_index" flag:"shardIndex"`
+	ShardTotal   string `yaml:"shard_total" flag:"shardTotal"`
+	RetryAttempt string `yaml:"retry_attempt" flag:"retryAttempt"`
+
+	OmitEmails string   `yaml:"omit_emails" flag:"omitEmails"`
+	Redact     []string `yaml:"redact" flag:"redact"`
+
+	SetExitCode string `yaml:"set_exit_code" flag:"setExitCode"`
+	FailOn      string `yaml:"fail_on" flag:"failOn"`
+	MaxFailures string `yaml:"max_failures" flag:"maxFailures"`
+	Baseline    string `yaml:"baseline" flag:"baseline"`
+	Quarantine  string `yaml:"quarantine" flag:"quarantine"`
+	SoftFail    string `yaml:"soft_fail" flag:"softFail"`
+	SpoolDir    string `yaml:"spool_dir" flag:"spoolDir"`
+	JobSummary  string `yaml:"job_summary" flag:"jobSummary"`
+	ExitPolicy  string `yaml:"exit_policy" flag:"exitPolicy"`
+	LogTail     string `yaml:"log_tail" flag:"logTail"`
+	SinceStart  string `yaml:"since_start" flag:"sinceStart"`
+	Interval    string `yaml:"interval" flag:"interval"`
+	Endpoint    string `yaml:"endpoint" flag:"endpoint"`
+	Debug       string `yaml:"debug" flag:"debug"`
+
+	// Projects splits a monorepo into separate uploads
+	Projects []Project `yaml:"projects"`
+}
+
+// Project is one part of a monorepo, uploaded as its own run with its own
+// reports and upload token
+type Project struct {
+	Slug string `yaml:"slug,omitempty" json:"slug"`
+	// Path is the project directory relative to the repository root
+	Path string `yaml:"path" json:"path"`
+	// Files are report globs relative to Path, the default patterns are
+	// searched in Path when empty
+	Files Patterns `yaml:"files,omitempty" json:"files"`
+	// TokenEnv is the env var holding the project's upload token
+	TokenEnv string `yaml:"token_env,omitempty" json:"token_env"`
+}
+
+// Patterns are report globs, a single one can be given as a plain string in
+// the config file
+type Patterns []string
+
+func (p *Patterns) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = Patterns{value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(p))
+}
+
+// Load reads the config at path. A missing file is an empty config, unless
+// the path was asked for explicitly.
+func Load(path string, explicit bool) (Config, error) {
+	cfg := Config{}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, cfg.validate(path)
+}
+
+func (c Config) validate(path string) error {
+	for i, p := range c.Projects {
+		if p.Path == "" {
+			return fmt.Errorf("invalid config %s: projects[%d] is missing a path", path, i)
+		}
+	}
+	return nil
+}
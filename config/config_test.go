@@ -0,0 +1,179 @@
+package config_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/config"
+)
+
+func TestLoadProjects(t *testing.T) {
+	path := writeConfig(t, `
+projects:
+  - path: services/api
+    files: build/test-results/*.xml
+    token_env: TR_API_TOKEN
+    slug: acme/api
+  - path: services/web
+    files:
+      - reports/**/*.xml
+      - "!reports/flaky.xml"
+`)
+
+	cfg, err := config.Load(path, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.Project{
+		{Path: "services/api", Files: config.Patterns{"build/test-results/*.xml"}, TokenEnv: "TR_API_TOKEN", Slug: "acme/api"},
+		{Path: "services/web", Files: config.Patterns{"reports/**/*.xml", "!reports/flaky.xml"}},
+	}, cfg.Projects)
+}
+
+func TestLoadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.DefaultPath)
+
+	cfg, err := config.Load(path, false)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Projects)
+
+	_, err = config.Load(path, true)
+	assert.Error(t, err)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, content := range []string{
+		"projects:\n  - files: '*.xml'\n",
+		"projects:\n  - path: a\n    unknown: true\n",
+		"projects: [",
+	} {
+		_, err := config.Load(writeConfig(t, content), true)
+		assert.Error(t, err, content)
+	}
+
+	cfg, err := config.Load(writeConfig(t, ""), true)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Projects)
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), config.DefaultPath)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+type listValue []string
+
+func (l *listValue) String() string { return strings.Join(*l, ",") }
+func (l *listValue) Get() any       { return []string(*l) }
+func (l *listValue) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func testFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("reporter", flag.ContinueOnError)
+	flags.String("file", "", "")
+	flags.String("branch", "", "")
+	flags.String("slug", "", "")
+	flags.Bool("describe", false, "")
+	flags.Int("maxChangedFiles", 500, "")
+	flags.String("endpoint", "https://default.example.com", "")
+	flags.Var(&listValue{}, "exclude", "")
+	return flags
+}
+
+func TestApply(t *testing.T) {
+	path := writeConfig(t, `
+file: build/*.xml
+exclude: [skipped.xml, "*-flaky.xml"]
+branch: from-config
+slug: acme/config
+describe: true
+endpoint: https://config.example.com
+`)
+	cfg, err := config.Load(path, true)
+	require.NoError(t, err)
+
+	t.Setenv("TR_TEST_SLUG", "acme/env")
+	t.Setenv("TR_TEST_ENDPOINT", "https://env.example.com")
+	t.Setenv("TR_TEST_BRANCH", "")
+
+	flags := testFlags()
+	require.NoError(t, flags.Parse([]string{"-endpoint", "https://flag.example.com"}))
+
+	sources, err := cfg.Apply(flags, map[string]string{
+		"slug":     "TR_TEST_SLUG",
+		"endpoint": "TR_TEST_ENDPOINT",
+		"branch":   "TR_TEST_BRANCH",
+	}, path)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		flag   string
+		value  string
+		source config.Source
+		found  bool
+	}{
+		{"endpoint", "https://flag.example.com", config.Source{Kind: config.SourceFlag, Name: "-endpoint"}, true},
+		{"slug", "acme/env", config.Source{Kind: config.SourceEnv, Name: "TR_TEST_SLUG"}, true},
+		{"branch", "from-config", config.Source{Kind: config.SourceConfig, Name: path}, true},
+		{"file", "build/*.xml", config.Source{Kind: config.SourceConfig, Name: path}, true},
+		{"exclude", "skipped.xml,*-flaky.xml", config.Source{Kind: config.SourceConfig, Name: path}, true},
+		{"describe", "true", config.Source{Kind: config.SourceConfig, Name: path}, true},
+		{"maxChangedFiles", "500", config.Source{}, false},
+	} {
+		assert.Equal(t, tt.value, flags.Lookup(tt.flag).Value.String(), tt.flag)
+		source, found := sources[tt.flag]
+		assert.Equal(t, tt.found, found, tt.flag)
+		assert.Equal(t, tt.source, source, tt.flag)
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, "max_changed_files: lots\n"), true)
+	require.NoError(t, err)
+	_, err = cfg.Apply(testFlags(), nil, config.DefaultPath)
+	assert.EqualError(t, err, `invalid config .testrecall.yml: max_changed_files: parse error`)
+
+	t.Setenv("TR_TEST_DESCRIBE", "maybe")
+	_, err = config.Config{}.Apply(testFlags(), map[string]string{"describe": "TR_TEST_DESCRIBE"}, config.DefaultPath)
+	assert.EqualError(t, err, `invalid TR_TEST_DESCRIBE: parse error`)
+
+	// settings of flags the command does not have are ignored
+	t.Setenv("TR_TEST_JOB", "unit")
+	sources, err := config.Config{Host: "ci-1"}.Apply(testFlags(), map[string]string{"job": "TR_TEST_JOB"}, config.DefaultPath)
+	assert.NoError(t, err)
+	assert.Empty(t, sources)
+}
+
+func TestShow(t *testing.T) {
+	cfg := config.Config{
+		Slug:     "acme/config",
+		Projects: []config.Project{{Path: "services/api", TokenEnv: "TR_API_TOKEN"}},
+	}
+
+	flags := testFlags()
+	require.NoError(t, flags.Parse([]string{"-exclude", "a.xml", "-exclude", "b.xml"}))
+	sources, err := cfg.Apply(flags, nil, config.DefaultPath)
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, cfg.Show(out, flags, sources))
+	assert.Equal(t, `file: "" # default
+exclude: [a.xml, b.xml] # flag -exclude
+branch: "" # default
+describe: false # default
+max_changed_files: 500 # default
+slug: acme/config # config .testrecall.yml
+endpoint: https://default.example.com # default
+projects:
+  - path: services/api
+    token_env: TR_API_TOKEN
+`, out.String())
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a config key with the values it gives the flag it defaults
+type setting struct {
+	key    string
+	flag   string
+	values []string
+}
+
+// settings lists the flag settings in the order they are declared
+func (c Config) settings() []setting {
+	settings := []setting{}
+
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, found := field.Tag.Lookup("flag")
+		if !found {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+
+		s := setting{key: key, flag: name}
+		switch value := v.Field(i).Interface().(type) {
+		case string:
+			if value != "" {
+				s.values = []string{value}
+			}
+		case []string:
+			s.values = value
+		case Patterns:
+			s.values = value
+		}
+		settings = append(settings, s)
+	}
+	return settings
+}
+
+// Apply fills each flag that was not given on the command line from its env
+// var in envs, or else from the config file at path, and returns where every
+// flag that is no longer a default got its value from. Settings for flags
+// the command does not have are left alone.
+func (c Config) Apply(flags *flag.FlagSet, envs map[string]string, path string) (map[string]Source, error) {
+	sources := map[string]Source{}
+	flags.Visit(func(f *flag.Flag) {
+		sources[f.Name] = Source{Kind: SourceFlag, Name: "-" + f.Name}
+	})
+
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, set := sources[name]; set || flags.Lookup(name) == nil {
+			continue
+		}
+		value := os.Getenv(envs[name])
+		if value == "" {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", envs[name], err)
+		}
+		sources[name] = Source{Kind: SourceEnv, Name: envs[name]}
+	}
+
+	for _, s := range c.settings() {
+		if _, set := sources[s.flag]; set || len(s.values) == 0 || flags.Lookup(s.flag) == nil {
+			continue
+		}
+		for _, value := range s.values {
+			if err := flags.Set(s.flag, value); err != nil {
+				return nil, fmt.Errorf("invalid config %s: %s: %w", path, s.key, err)
+			}
+		}
+		sources[s.flag] = Source{Kind: SourceConfig, Name: path}
+	}
+	return sources, nil
+}
+
+// Show writes the effective configuration as yaml, each setting commented
+// with where its value came from
+func (c Config) Show(w io.Writer, flags *flag.FlagSet, sources map[string]Source) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		f := flags.Lookup(s.flag)
+		if f == nil {
+			continue
+		}
+
+		value := valueNode(f)
+		value.LineComment = "default"
+		if source, found := sources[s.flag]; found {
+			value.LineComment = source.String()
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: s.key}, value)
+	}
+
+	if len(c.Projects) > 0 {
+		projects := &yaml.Node{}
+		if err := projects.Encode(c.Projects); err != nil {
+			return err
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "projects"}, projects)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// valueNode renders repeatable flags as a list, everything else as the
+// string the flag prints
+func valueNode(f *flag.Flag) *yaml.Node {
+	if getter, ok := f.Value.(flag.Getter); ok {
+		if list, ok := getter.Get().([]string); ok {
+			node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, item := range list {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+			}
+			return node
+		}
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: f.Value.String()}
+	if node.Value == "" {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return node
+}
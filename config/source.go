@@ -0,0 +1,23 @@
+package config
+
+const (
+	SourceFlag   = "flag"
+	SourceEnv    = "env"
+	SourceGit    = "git"
+	SourceConfig = "config"
+	SourceSystem = "system"
+)
+
+// Source records where a setting was resolved from, e.g. the env var a
+// vendor read or the git command that was run
+type Source struct {
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return s.Kind + " " + s.Name
+}
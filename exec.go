@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+const (
+	// exitPolicyCommand exits with the test command's status
+	exitPolicyCommand = "command"
+	// exitPolicyReporter exits as a bare upload would, failing on failed
+	// tests, invalid reports or a failed upload
+	exitPolicyReporter = "reporter"
+	// exitPolicyEither exits with the command's status when it failed, and
+	// the reporter's otherwise
+	exitPolicyEither = "either"
+)
+
+// runExec runs the test command, then uploads whatever reports it left
+// behind, however it exited
+func runExec(o *options, args []string) {
+	if len(args) == 0 {
+		o.flags.Usage()
+		os.Exit(2)
+	}
+	if err := validExitPolicy(o.exitPolicy); err != nil {
+		o.fatal(err)
+	}
+
+	policy, err := o.policy()
+	if err != nil {
+		o.fatal(err)
+	}
+	// a missing -newerThan marker fails before the tests run, not after
+	if _, err := o.freshAfter(); err != nil {
+		o.fatal(err)
+	}
+
+	run := reporter.RunCommand(args, o.logTail, o.logger)
+	o.logger.Debugf("%s exited with %d after %dms", args[0], run.ExitCode, run.DurationMS)
+
+	o.started = run.StartedAt
+	payloads := o.execPayloads(&run)
+	results, _ := o.upload(payloads)
+	status := o.exitCode(policy, results)
+	os.Exit(exitStatus(o.exitPolicy, run.ExitCode, status))
+}
+
+// execPayloads are the projects the command left reports for. When it
+// failed without leaving any, a crash report with its output is uploaded
+// in their place.
+func (o *options) execPayloads(run *reporter.CommandRun) []reporter.RequestPayload {
+	all, err := o.payloads()
+	if errors.Is(err, reporter.ErrNoReports) {
+		o.logger.Debug(err)
+	} else if err != nil {
+		o.fatal(err)
+	}
+
+	payloads := []reporter.RequestPayload{}
+	for _, payload := range all {
+		if payload.HasReports() {
+			payload.RequestData.Command = run
+			payloads = append(payloads, payload)
+		}
+	}
+	if len(payloads) > 0 {
+		return payloads
+	}
+
+	if run.ExitCode == 0 {
+		o.logger.Warnf("no reports found after running %s, nothing to upload", run.Args[0])
+		return payloads
+	}
+
+	o.logger.Warnf("%s failed without writing a report, uploading its output instead", run.Args[0])
+	crash, err := o.payload()
+	if err != nil {
+		o.fatal(err)
+	}
+	crash.RequestData.Command = run
+	crash.RequestData.Filenames = []string{reporter.CrashFilename}
+	crash.RequestData.RunData = [][]byte{reporter.CrashReport(*run)}
+	return []reporter.RequestPayload{crash}
+}
+
+// validExitPolicy checks -exitPolicy, for the commands that run a test
+// command
+func validExitPolicy(policy string) error {
+	switch policy {
+	case exitPolicyCommand, exitPolicyReporter, exitPolicyEither:
+		return nil
+	}
+	return fmt.Errorf("invalid -exitPolicy %q, expected command, reporter or either", policy)
+}
+
+func exitStatus(policy string, command, upload int) int {
+	switch policy {
+	case exitPolicyReporter:
+		return upload
+	case exitPolicyEither:
+		if command != 0 {
+			return command
+		}
+		return upload
+	}
+	return command
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidExitPolicy(t *testing.T) {
+	for _, policy := range []string{exitPolicyCommand, exitPolicyReporter, exitPolicyEither} {
+		assert.NoError(t, validExitPolicy(policy))
+	}
+	assert.EqualError(t, validExitPolicy("both"), `invalid -exitPolicy "both", expected command, reporter or either`)
+}
+
+func TestExitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		policy  string
+		command int
+		upload  int
+		want    int
+	}{
+		{exitPolicyCommand, 0, 0, 0},
+		{exitPolicyCommand, 0, 1, 0},
+		{exitPolicyCommand, 2, 0, 2},
+		{exitPolicyCommand, 2, 6, 2},
+		{exitPolicyReporter, 0, 1, 1},
+		{exitPolicyReporter, 2, 0, 0},
+		{exitPolicyReporter, 2, 6, 6},
+		{exitPolicyEither, 0, 0, 0},
+		{exitPolicyEither, 0, 6, 6},
+		{exitPolicyEither, 2, 0, 2},
+		{exitPolicyEither, 2, 6, 2},
+	} {
+		assert.Equal(t, tt.want, exitStatus(tt.policy, tt.command, tt.upload), "%s %d %d", tt.policy, tt.command, tt.upload)
+	}
+}
@@ -0,0 +1,333 @@
+// Package git reads repository metadata straight from the .git directory,
+// for environments that have a checkout but no git binary.
+package git
+
+import (
+	"bufio"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	headsPrefix   = "refs/heads/"
+	remotesPrefix = "refs/remotes/"
+	tagsPrefix    = "refs/tags/"
+	symrefPrefix  = "ref: "
+	gitdirPrefix  = "gitdir: "
+)
+
+var ErrNotRepository = errors.New("not a git repository (or any of the parent directories)")
+
+type Repo struct {
+	// GitDir holds HEAD, for worktrees this is .git/worktrees/<name>
+	GitDir string
+	// CommonDir holds refs and packed-refs shared by all worktrees
+	CommonDir string
+}
+
+// Open finds the repository containing dir, walking up to the filesystem root
+func Open(dir string) (*Repo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return openGitDir(dotGit)
+			}
+			return openGitFile(dotGit)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotRepository
+		}
+		dir = parent
+	}
+}
+
+// openGitFile follows a `gitdir: <path>` file, as used by worktrees and
+// submodules
+func openGitFile(path string) (*Repo, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, gitdirPrefix) {
+		return nil, fmt.Errorf("invalid gitdir file: %s", path)
+	}
+
+	gitDir := strings.TrimPrefix(line, gitdirPrefix)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return openGitDir(gitDir)
+}
+
+func openGitDir(gitDir string) (*Repo, error) {
+	repo := &Repo{GitDir: gitDir, CommonDir: gitDir}
+
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return repo, nil
+	}
+
+	commonDir := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	repo.CommonDir = filepath.Clean(commonDir)
+	return repo, nil
+}
+
+// Head returns the commit HEAD points at, and the ref it points through, or
+// an empty ref when HEAD is detached
+func (r *Repo) Head() (sha string, ref string, err error) {
+	content, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", "", err
+	}
+
+	head := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(head, symrefPrefix) {
+		return head, "", nil
+	}
+
+	ref = strings.TrimPrefix(head, symrefPrefix)
+	sha, err = r.Resolve(ref)
+	return sha, ref, err
+}
+
+// Resolve returns the commit a full ref name such as refs/heads/main points
+// at, following symbolic refs
+func (r *Repo) Resolve(ref string) (string, error) {
+	for depth := 0; depth < 10; depth++ {
+		content, err := r.readLooseRef(ref)
+		if errors.Is(err, os.ErrNotExist) {
+			return r.resolvePacked(ref)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		value := strings.TrimSpace(content)
+		if !strings.HasPrefix(value, symrefPrefix) {
+			return value, nil
+		}
+		ref = strings.TrimPrefix(value, symrefPrefix)
+	}
+	return "", fmt.Errorf("too many levels of symbolic refs: %s", ref)
+}
+
+func (r *Repo) readLooseRef(ref string) (string, error) {
+	// per-worktree refs live in the worktree's git dir, the rest are shared
+	content, err := os.ReadFile(filepath.Join(r.GitDir, filepath.FromSlash(ref)))
+	if errors.Is(err, os.ErrNotExist) && r.CommonDir != r.GitDir {
+		content, err = os.ReadFile(filepath.Join(r.CommonDir, filepath.FromSlash(ref)))
+	}
+	return string(content), err
+}
+
+func (r *Repo) resolvePacked(ref string) (string, error) {
+	packed, err := r.packedRefs()
+	if err != nil {
+		return "", err
+	}
+
+	if sha, found := packed[ref]; found {
+		return sha, nil
+	}
+	return "", fmt.Errorf("unknown ref: %s", ref)
+}
+
+// packedRefs reads packed-refs into ref name -> sha
+func (r *Repo) packedRefs() (map[string]string, error) {
+	refs, _, err := r.readPackedRefs()
+	return refs, err
+}
+
+// readPackedRefs reads packed-refs into ref name -> sha, and annotated tag
+// name -> the commit from the `^sha` line that follows it
+func (r *Repo) readPackedRefs() (map[string]string, map[string]string, error) {
+	refs, peeled := map[string]string{}, map[string]string{}
+
+	f, err := os.Open(filepath.Join(r.CommonDir, "packed-refs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return refs, peeled, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	last := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "^") {
+			peeled[last] = strings.TrimPrefix(line, "^")
+			continue
+		}
+
+		sha, name, found := strings.Cut(line, " ")
+		if found {
+			refs[name] = sha
+			last = name
+		}
+	}
+	return refs, peeled, scanner.Err()
+}
+
+// Refs lists every loose and packed ref under prefix, loose refs winning
+// over packed ones like git does
+func (r *Repo) Refs(prefix string) (map[string]string, error) {
+	packed, err := r.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[string]string{}
+	for name, sha := range packed {
+		if strings.HasPrefix(name, prefix) {
+			refs[name] = sha
+		}
+	}
+
+	root := filepath.Join(r.CommonDir, filepath.FromSlash(prefix))
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.CommonDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if sha, err := r.Resolve(name); err == nil {
+			refs[name] = sha
+		}
+		return nil
+	})
+	return refs, err
+}
+
+// Branch returns the short name of the checked out branch. On a detached
+// HEAD it picks a branch pointing at the same commit, preferring local
+// branches over remote-tracking ones, and returns "" if there is none.
+func (r *Repo) Branch() (string, error) {
+	sha, ref, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(ref, headsPrefix) {
+		return strings.TrimPrefix(ref, headsPrefix), nil
+	}
+
+	for _, prefix := range []string{headsPrefix, remotesPrefix} {
+		refs, err := r.Refs(prefix)
+		if err != nil {
+			return "", err
+		}
+
+		names := []string{}
+		for name, refSHA := range refs {
+			if refSHA == sha && !strings.HasSuffix(name, "/HEAD") {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		sort.Strings(names)
+		return shortBranch(names[0]), nil
+	}
+	return "", nil
+}
+
+// shortBranch strips refs/heads/ or refs/remotes/<remote>/ from a ref
+func shortBranch(ref string) string {
+	if strings.HasPrefix(ref, headsPrefix) {
+		return strings.TrimPrefix(ref, headsPrefix)
+	}
+
+	remote := strings.TrimPrefix(ref, remotesPrefix)
+	if _, branch, found := strings.Cut(remote, "/"); found {
+		return branch
+	}
+	return remote
+}
+
+// Tags returns the sorted names of tags pointing at sha. Annotated tags are
+// peeled when packed-refs records the commit or the tag object is loose.
+func (r *Repo) Tags(sha string) ([]string, error) {
+	refs, err := r.Refs(tagsPrefix)
+	if err != nil {
+		return nil, err
+	}
+	_, peeled, err := r.readPackedRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	for name, target := range refs {
+		if commit, found := peeled[name]; found {
+			target = commit
+		} else {
+			target = r.peel(target)
+		}
+
+		if target == sha {
+			tags = append(tags, strings.TrimPrefix(name, tagsPrefix))
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// peel returns the object an annotated tag points at, or sha itself when it
+// is not a loose tag object
+func (r *Repo) peel(sha string) string {
+	if len(sha) < 3 {
+		return sha
+	}
+
+	f, err := os.Open(filepath.Join(r.CommonDir, "objects", sha[:2], sha[2:]))
+	if err != nil {
+		return sha
+	}
+	defer f.Close()
+
+	z, err := zlib.NewReader(f)
+	if err != nil {
+		return sha
+	}
+	defer z.Close()
+
+	// "tag <size>\x00object <sha>\ntype commit\n..."
+	header := make([]byte, 128)
+	n, _ := io.ReadFull(z, header)
+	kind, rest, _ := strings.Cut(string(header[:n]), "\x00")
+	if !strings.HasPrefix(kind, "tag ") || !strings.HasPrefix(rest, "object ") {
+		return sha
+	}
+
+	object, _, _ := strings.Cut(strings.TrimPrefix(rest, "object "), "\n")
+	return object
+}
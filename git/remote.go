@@ -0,0 +1,73 @@
+package git
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RemoteURL reads the url of a remote from the repository config
+func (r *Repo) RemoteURL(name string) (string, error) {
+	f, err := os.Open(filepath.Join(r.CommonDir, "config"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	section := `[remote "` + name + `"]`
+	inSection := false
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if found && strings.TrimSpace(key) == "url" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// SlugFromURL returns the owner/repo path of a remote url in any of the
+// forms git accepts: https://host/owner/repo.git, ssh://git@host:22/owner/repo,
+// or scp-like git@host:owner/repo.git. Nested GitLab groups are kept, e.g.
+// group/subgroup/repo.
+func SlugFromURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+
+	var path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return ""
+		}
+		path = u.Path
+	} else if _, scpPath, found := strings.Cut(remote, ":"); found && !filepath.IsAbs(remote) {
+		// scp-like, user@host:path
+		path = scpPath
+	} else {
+		// a local path is not hosted anywhere
+		return ""
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	path = strings.TrimSuffix(path, "/")
+	if !strings.Contains(path, "/") {
+		return ""
+	}
+	return path
+}
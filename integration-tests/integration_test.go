@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -86,6 +88,52 @@ func TestUpload(t *testing.T) {
 	assert.NoError(t, err, string(out))
 }
 
+func TestMissingFile(t *testing.T) {
+	executable := executablePath(t)
+	setEnv(t, "TR_UPLOAD_TOKEN", "123")
+
+	cmd := exec.Command(executable, "-file", "nope.xml", "-endpoint", "http://127.0.0.1:1")
+	cmd.Dir = t.TempDir()
+	out, err := cmd.CombinedOutput()
+
+	exitErr := &exec.ExitError{}
+	if assert.ErrorAs(t, err, &exitErr, string(out)) {
+		assert.Equal(t, reporter.ExitNoTests, exitErr.ExitCode(), string(out))
+	}
+	assert.Contains(t, string(out), "unable to find file to upload results at: nope.xml")
+}
+
+func TestMissingToken(t *testing.T) {
+	executable := executablePath(t)
+
+	// -softFail ignores outages, not a reporter that cannot be set up
+	cmd := exec.Command(executable, "-file", "integration-tests/fixtures/small.xml", "-softFail", "-endpoint", "http://127.0.0.1:1")
+	cmd.Dir = ".."
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "TR_UPLOAD_TOKEN=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	out, err := cmd.CombinedOutput()
+
+	exitErr := &exec.ExitError{}
+	if assert.ErrorAs(t, err, &exitErr, string(out)) {
+		assert.Equal(t, reporter.ExitSetup, exitErr.ExitCode(), string(out))
+	}
+	assert.Contains(t, string(out), "TR_UPLOAD_TOKEN must be set")
+}
+
+// executablePath is the absolute path of the built reporter
+func executablePath(t *testing.T) string {
+	path := "../dist/linux_linux_amd64_v1/reporter"
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+		path = "../dist/macos_darwin_arm64/reporter"
+	}
+	executable, err := filepath.Abs(path)
+	assert.NoError(t, err)
+	return executable
+}
+
 func runCmd(dir, c string) ([]byte, error) {
 	args := strings.Split(c, " ")
 	cmd := exec.Command(args[0], args[1:]...)
@@ -3,9 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/sirupsen/logrus"
 	"github.com/testrecall/reporter/reporter"
 )
 
@@ -15,112 +17,221 @@ var (
 	Version   = "unknown"
 	Date      = "unknown"
 	Commit    = "unknown"
+)
 
-	printVersion = flag.Bool("version", false, "print version")
-
-	debug       = flag.Bool("debug", false, "debug log level")
-	setExitCode = flag.String("setExitCode", "", "[true]/false', exits 1 if tests failed")
-
-	junitFile = flag.String("file", "", "junit file")
-	hostName  = flag.String("host", "", "host name")
-
-	gitBranch = flag.String("branch", "", "git branch")
-	gitSHA    = flag.String("sha", "", "git sha")
-	gitTag    = flag.String("tag", "", "git tag")
-	isPr      = flag.String("pr", "", "true/false/[unknown] is git PR")
+type command struct {
+	name    string
+	usage   string
+	summary string
+	flags   func(o *options, fs *flag.FlagSet)
+	run     func(o *options, args []string)
+	// passthrough stops parsing flags at the first argument, which starts a
+	// command of its own
+	passthrough bool
+}
 
-	slug        = flag.String("slug", "", "repo slug")
-	ciName      = flag.String("ciName", "", "ci runner name")
-	buildNumber = flag.String("buildnumber", "", "build number for labeling runs")
-	buildURL    = flag.String("buildurl", "", "build url to link back to")
-	job         = flag.String("job", "", "build url to link back to")
-)
+var commands = []command{
+	{
+		name:    "upload",
+		usage:   "[upload]",
+		summary: "upload test reports, the default when no command is given",
+		flags: func(o *options, fs *flag.FlagSet) {
+			o.uploadFlags(fs)
+			fs.BoolVar(&o.printVersion, "version", false, "print version")
+		},
+		run: runUpload,
+	},
+	{
+		name:    "exec",
+		usage:   "exec [flags] -- <test command>",
+		summary: "run the test command, then upload its reports however it exited",
+		flags: func(o *options, fs *flag.FlagSet) {
+			o.uploadFlags(fs)
+			fs.StringVar(&o.exitPolicy, "exitPolicy", exitPolicyCommand, "exit with the status of the test command, the reporter, or either when the command failed")
+			fs.IntVar(&o.logTail, "logTail", reporter.DefaultLogTail, "lines of output to upload when the test command fails without writing a report")
+			fs.BoolVar(&o.sinceStart, "sinceStart", false, "skip report files last modified before the test command started")
+		},
+		run:         runExec,
+		passthrough: true,
+	},
+	{
+		name:    "watch",
+		usage:   "watch [flags] <dir> [-- <test command>]",
+		summary: "upload reports from a directory as they are written, as parts of one run",
+		flags: func(o *options, fs *flag.FlagSet) {
+			o.uploadFlags(fs)
+			fs.DurationVar(&o.interval, "interval", reporter.DefaultWatchInterval, "how often to scan for reports")
+			fs.StringVar(&o.exitPolicy, "exitPolicy", exitPolicyCommand, "exit with the status of the test command, the reporter, or either when the command failed")
+		},
+		run:         runWatch,
+		passthrough: true,
+	},
+	{
+		name:    "validate",
+		summary: "check that every report file can be parsed, without uploading",
+		flags:   (*options).reportFlags,
+		run:     runValidate,
+	},
+	{
+		name:    "summary",
+		summary: "print the test totals and failed tests of the report files",
+		flags:   (*options).reportFlags,
+		run:     runSummary,
+	},
+	{
+		name:    "convert",
+		summary: "write the report files as one junit or json document",
+		flags: func(o *options, fs *flag.FlagSet) {
+			o.reportFlags(fs)
+			fs.StringVar(&o.format, "format", "junit", "output format, junit or json")
+			fs.StringVar(&o.output, "o", "-", "file to write, - for stdout")
+		},
+		run: runConvert,
+	},
+	{
+		name:    "doctor",
+		summary: "print the detected CI vendor, metadata, report files and connectivity",
+		flags:   (*options).uploadFlags,
+		run:     runDoctor,
+	},
+	{
+		name:    "config",
+		usage:   "config show",
+		summary: "print the effective configuration and where each value came from",
+		flags:   (*options).uploadFlags,
+		run:     runConfig,
+	},
+	{
+		name:    "version",
+		summary: "print version",
+		flags:   func(o *options, fs *flag.FlagSet) {},
+		run:     func(o *options, args []string) { printVersion() },
+	},
+}
 
 func main() {
-	flag.Parse()
+	args := os.Args[1:]
+	cmd := commands[0]
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name := args[0]
+		if name == "help" {
+			help(args[1:])
+			return
+		}
 
-	if *printVersion {
-		fmt.Printf("Version: %s\nCommit: %s\nBuilt at: %s\n", Version, Commit, Date)
-		os.Exit(0)
+		found := false
+		for _, c := range commands {
+			if c.name == name {
+				cmd, found = c, true
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+			printUsage(os.Stderr)
+			os.Exit(2)
+		}
+		args = args[1:]
 	}
 
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	o := &options{}
+	fs := newFlagSet(o, cmd)
+	args, err := parse(fs, args, cmd.passthrough)
+	if err != nil {
+		os.Exit(2)
+	}
 
-	if *debug {
-		logger.Level = logrus.TraceLevel
-	} else {
-		logger.Level = logrus.InfoLevel
+	if o.printVersion {
+		printVersion()
+		os.Exit(0)
 	}
 
-	flagsMap := mapFlags()
-	payload := reporter.RequestPayload{
-		Filename:    *junitFile,
-		UploadToken: "",
-
-		RequestData: reporter.RequestData{
-			RunData:   [][]byte{},
-			Filenames: []string{*junitFile},
-
-			Hostname:        *hostName,
-			ReporterVersion: Version + "-" + Commit,
-			Flags:           flagsMap,
-
-			Branch: *gitBranch,
-			SHA:    *gitSHA,
-			Tag:    *gitTag,
-			PR:     *isPr,
-
-			Slug:        *slug,
-			CIName:      *ciName,
-			BuildNumber: *buildNumber,
-			BuildURL:    *buildURL,
-			Job:         *job,
-		},
+	o.load(fs)
+	cmd.run(o, args)
+}
 
-		Logger: logger,
+// parse allows flags after positional args, as in `config show -debug`.
+// Everything after -- is positional, as is everything after the first
+// positional arg with passthrough.
+func parse(fs *flag.FlagSet, args []string, passthrough bool) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		rest := fs.Args()
+		if len(rest) == 0 || passthrough {
+			return append(positional, rest...), nil
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
+}
 
-	payload.Setup()
+func newFlagSet(o *options, cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	cmd.flags(o, fs)
+	describeEnvs(fs)
 
-	url := RemoteURL
-	if newURL, found := os.LookupEnv("TR_SITE"); found {
-		url = newURL
+	fs.Usage = func() {
+		usage := cmd.usage
+		if usage == "" {
+			usage = cmd.name
+		}
+		if !strings.Contains(usage, "[flags]") {
+			usage += " [flags]"
+		}
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\n%s\n", program(), usage, cmd.summary)
+		if cmd.name == commands[0].name {
+			fmt.Fprintln(fs.Output())
+			printCommands(fs.Output())
+		}
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	sender := reporter.NewSender(logger)
-	if err := sender.Send(url, payload); err != nil {
-		logger.Debug("upload failed!")
-		logger.Fatalln(err)
+// help prints the overview, or the flags of one command
+func help(args []string) {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return
 	}
-	logger.Debug("upload success!")
-
-	fails, xmlValid := payload.FailureCount()
-	if shouldExitOnFail(*setExitCode) {
-		if !xmlValid {
-			logger.Debugf("test xml is invalid")
-			os.Exit(1)
-		} else if fails > 0 {
-			logger.Debugf("exiting with failed tests: %v", fails)
-			os.Exit(1)
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			fs := newFlagSet(&options{}, c)
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return
 		}
 	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+	os.Exit(2)
 }
 
-func mapFlags() map[string]string {
-	flags := map[string]string{}
-	flag.VisitAll(func(f *flag.Flag) {
-		value := f.Value.String()
-		if value != "" {
-			flags[f.Name] = value
-		}
-	})
-	return flags
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\n", program())
+	printCommands(w)
+	fmt.Fprintf(w, "\nRun '%s help <command>' for the flags of a command.\n", program())
 }
 
-func shouldExitOnFail(s string) bool {
-	if s == "false" || s == "f" {
-		return false
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
 	}
-	return true
+}
+
+func program() string {
+	return filepath.Base(os.Args[0])
+}
+
+func printVersion() {
+	fmt.Printf("Version: %s\nCommit: %s\nBuilt at: %s\n", Version, Commit, Date)
 }
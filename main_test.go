@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintUsage(t *testing.T) {
+	out := &bytes.Buffer{}
+	printUsage(out)
+
+	assert.Contains(t, out.String(), "Usage: ")
+	for _, c := range commands {
+		assert.Contains(t, out.String(), "  "+c.name+" ")
+		assert.Contains(t, out.String(), c.summary)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/testrecall/reporter/config"
+	"github.com/testrecall/reporter/reporter"
+)
+
+// options holds the flags of every command, each command registers the
+// groups it uses
+type options struct {
+	printVersion bool
+	configFile   string
+	debug        bool
+
+	files       listFlag
+	exclude     listFlag
+	allDefaults bool
+	maxAge      time.Duration
+	newerThan   string
+	history     string
+
+	setExitCode string
+	failOn      string
+	maxFailures int
+	baseline    string
+	quarantine  string
+	softFail    bool
+	spoolDir    string
+	jobSummary  string
+	endpoint    string
+
+	hostName  string
+	gitBranch string
+	gitSHA    string
+	gitTag    string
+	describe  bool
+
+	changedFiles    bool
+	baseBranch      string
+	maxChangedFiles int
+	isPr            string
+
+	slug        string
+	ciName      string
+	buildNumber string
+	buildURL    string
+	job         string
+
+	omitEmails bool
+	redact     listFlag
+
+	shardIndex   string
+	shardTotal   string
+	retryAttempt string
+
+	format string
+	output string
+
+	exitPolicy string
+	logTail    int
+	sinceStart bool
+	interval   time.Duration
+	// started is when the test command run by exec started
+	started time.Time
+
+	flags   *flag.FlagSet
+	config  config.Config
+	sources map[string]config.Source
+	logger  *logrus.Logger
+}
+
+// reportFlags select the report files, every command has them
+func (o *options) reportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configFile, "config", config.DefaultPath, "config file")
+	fs.BoolVar(&o.debug, "debug", false, "debug log level")
+
+	fs.Var(&o.files, "file", "junit file or glob, repeatable, ** matches any directories and a leading ! excludes")
+	fs.Var(&o.exclude, "exclude", "glob of report files to skip, repeatable")
+	fs.BoolVar(&o.allDefaults, "allDefaults", false, "without -file, search every default location rather than the first with reports")
+	fs.DurationVar(&o.maxAge, "maxAge", 0, "skip report files last modified longer ago than this, e.g. 2h")
+	fs.StringVar(&o.newerThan, "newerThan", "", "skip report files last modified before this marker file")
+	fs.StringVar(&o.history, "history", "", "local test history file, to label failed tests new, persistent or flaky")
+}
+
+// uploadFlags describe the run being uploaded
+func (o *options) uploadFlags(fs *flag.FlagSet) {
+	o.reportFlags(fs)
+
+	fs.StringVar(&o.setExitCode, "setExitCode", "", "[true]/false', false only fails on -failOn upload")
+	fs.StringVar(&o.failOn, "failOn", strings.Join(reporter.DefaultFailOn, ","), "reasons to fail the run: failures, new-failures, errors, invalid, no-tests, upload")
+	fs.IntVar(&o.maxFailures, "maxFailures", 0, "failed tests tolerated before -failOn failures or new-failures fails the run")
+	fs.StringVar(&o.baseline, "baseline", "", "junit report or list of test ids known to fail, for -failOn new-failures")
+	fs.StringVar(&o.quarantine, "quarantine", "", "yaml list of tests whose failures do not fail the run, each with an owner and expiry date")
+	fs.BoolVar(&o.softFail, "softFail", false, "warn instead of failing the run when the upload fails")
+	fs.StringVar(&o.spoolDir, "spoolDir", "", "directory to keep failed uploads in, sent by the next run with the same -spoolDir")
+	fs.StringVar(&o.jobSummary, "jobSummary", "", "file to append a markdown summary of the run to, defaults to $GITHUB_STEP_SUMMARY")
+	fs.StringVar(&o.endpoint, "endpoint", RemoteURL, "url to upload results to")
+
+	fs.StringVar(&o.hostName, "host", "", "host name")
+	fs.StringVar(&o.gitBranch, "branch", "", "git branch")
+	fs.StringVar(&o.gitSHA, "sha", "", "git sha")
+	fs.StringVar(&o.gitTag, "tag", "", "git tag")
+	fs.BoolVar(&o.describe, "describe", false, "label the run with git describe")
+
+	fs.BoolVar(&o.changedFiles, "changedFiles", false, "upload the merge-base and files changed since the base branch")
+	fs.StringVar(&o.baseBranch, "baseBranch", "", "branch to diff against for -changedFiles, defaults to the pull request target")
+	fs.IntVar(&o.maxChangedFiles, "maxChangedFiles", reporter.DefaultMaxChangedFiles, "maximum changed files to upload")
+	fs.StringVar(&o.isPr, "pr", "", "true/false/[unknown] is git PR")
+
+	fs.StringVar(&o.slug, "slug", "", "repo slug, owner/repo, detected from CI env or the origin remote")
+	fs.StringVar(&o.ciName, "ciName", "", "ci runner name")
+	fs.StringVar(&o.buildNumber, "buildnumber", "", "build number for labeling runs")
+	fs.StringVar(&o.buildURL, "buildurl", "", "build url to link back to")
+	fs.StringVar(&o.job, "job", "", "ci job name")
+
+	fs.BoolVar(&o.omitEmails, "omitEmails", false, "do not upload commit author and committer emails")
+	fs.Var(&o.redact, "redact", "regular expression to mask in uploaded reports and metadata, repeatable")
+
+	fs.StringVar(&o.shardIndex, "shardIndex", "", "0-based index of this parallel node")
+	fs.StringVar(&o.shardTotal, "shardTotal", "", "total number of parallel nodes")
+	fs.StringVar(&o.retryAttempt, "retryAttempt", "", "1-based attempt number of this job")
+}
+
+// flagEnvs are env vars that default a flag ahead of the config file
+var flagEnvs = map[string]string{
+	"config":          "TR_CONFIG",
+	"debug":           "TR_DEBUG",
+	"setExitCode":     "TR_SET_EXIT_CODE",
+	"failOn":          "TR_FAIL_ON",
+	"maxFailures":     "TR_MAX_FAILURES",
+	"baseline":        "TR_BASELINE",
+	"quarantine":      "TR_QUARANTINE",
+	"softFail":        "TR_SOFT_FAIL",
+	"spoolDir":        "TR_SPOOL_DIR",
+	"jobSummary":      "TR_JOB_SUMMARY",
+	"file":            "TR_FILE",
+	"exclude":         "TR_EXCLUDE",
+	"allDefaults":     "TR_ALL_DEFAULTS",
+	"maxAge":          "TR_MAX_AGE",
+	"newerThan":       "TR_NEWER_THAN",
+	"history":         "TR_HISTORY",
+	"sinceStart":      "TR_SINCE_START",
+	"interval":        "TR_INTERVAL",
+	"host":            "TR_HOST",
+	"branch":          "TR_BRANCH",
+	"sha":             "TR_SHA",
+	"tag":             "TR_TAG",
+	"describe":        "TR_DESCRIBE",
+	"changedFiles":    "TR_CHANGED_FILES",
+	"baseBranch":      "TR_BASE_BRANCH",
+	"maxChangedFiles": "TR_MAX_CHANGED_FILES",
+	"pr":              "TR_PR",
+	"slug":            "TR_SLUG",
+	"ciName":          "TR_CI_NAME",
+	"buildnumber":     "TR_BUILD_NUMBER",
+	"buildurl":        "TR_BUILD_URL",
+	"job":             "TR_JOB",
+	"omitEmails":      "TR_OMIT_EMAILS",
+	"redact":          "TR_REDACT",
+	"endpoint":        "TR_SITE",
+	"exitPolicy":      "TR_EXIT_POLICY",
+	"logTail":         "TR_LOG_TAIL",
+	"shardIndex":      "TR_SHARD_INDEX",
+	"shardTotal":      "TR_SHARD_TOTAL",
+	"retryAttempt":    "TR_RETRY_ATTEMPT",
+}
+
+// describeEnvs adds the env var of each flag to its help
+func describeEnvs(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if env, found := flagEnvs[f.Name]; found {
+			f.Usage += " (env " + env + ")"
+		}
+	})
+}
+
+// load layers env vars and the config file under the parsed flags
+func (o *options) load(fs *flag.FlagSet) {
+	o.flags = fs
+	o.logger = logrus.New()
+	o.logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+
+	explicitConfig := o.isFlagSet("config")
+	if path := os.Getenv(flagEnvs["config"]); path != "" && !explicitConfig {
+		o.configFile, explicitConfig = path, true
+	}
+
+	var err error
+	o.config, err = config.Load(o.configFile, explicitConfig)
+	if err != nil {
+		o.fatal(err)
+	}
+	o.sources, err = o.config.Apply(fs, flagEnvs, o.configFile)
+	if err != nil {
+		o.fatal(err)
+	}
+
+	if o.debug {
+		o.logger.Level = logrus.TraceLevel
+	} else {
+		o.logger.Level = logrus.InfoLevel
+	}
+}
+
+// fatal logs an error that keeps the reporter from running, such as an
+// invalid flag or config file, and exits with ExitSetup
+func (o *options) fatal(err error) {
+	o.logger.Error(err)
+	os.Exit(reporter.ExitSetup)
+}
+
+// payload builds the upload for the repository from the flags
+func (o *options) payload() (reporter.RequestPayload, error) {
+	freshAfter, err := o.freshAfter()
+	if err != nil {
+		return reporter.RequestPayload{}, err
+	}
+
+	payload := reporter.RequestPayload{
+		Files:       o.files,
+		AllDefaults: o.allDefaults,
+		FreshAfter:  freshAfter,
+		Exclude:     o.exclude,
+		Redact:      o.redact,
+		UploadToken: "",
+		OmitEmails:  o.omitEmails,
+		Describe:    o.describe,
+
+		ChangedFiles:    o.changedFiles,
+		BaseBranch:      o.baseBranch,
+		MaxChangedFiles: o.maxChangedFiles,
+
+		RequestData: reporter.RequestData{
+			RunData:   [][]byte{},
+			Filenames: o.files,
+
+			Hostname:        o.hostName,
+			ReporterVersion: Version + "-" + Commit,
+			Flags:           o.mapFlags(),
+
+			Branch: o.gitBranch,
+			SHA:    o.gitSHA,
+			Tag:    o.gitTag,
+			PR:     o.isPr,
+
+			Slug:        o.slug,
+			CIName:      o.ciName,
+			BuildNumber: o.buildNumber,
+			BuildURL:    o.buildURL,
+			Job:         o.job,
+
+			ShardIndex:   o.shardIndex,
+			ShardTotal:   o.shardTotal,
+			RetryAttempt: o.retryAttempt,
+		},
+
+		Logger: o.logger,
+	}
+
+	for name, source := range o.sources {
+		if field, found := flagFields[name]; found {
+			payload.SetSource(field, source)
+		}
+	}
+	return payload, nil
+}
+
+// freshAfter is the latest of the freshness cutoffs asked for, zero when
+// none were
+func (o *options) freshAfter() (time.Time, error) {
+	cutoff := time.Time{}
+	if o.maxAge > 0 {
+		cutoff = time.Now().Add(-o.maxAge)
+	}
+	if o.newerThan != "" {
+		info, err := os.Stat(o.newerThan)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid -newerThan: %w", err)
+		}
+		if info.ModTime().After(cutoff) {
+			cutoff = info.ModTime()
+		}
+	}
+	if o.sinceStart && o.started.After(cutoff) {
+		cutoff = o.started
+	}
+	return cutoff, nil
+}
+
+// loadHistory reads the -history file, nil without one. A history that
+// cannot be read is started over rather than failing the run.
+func (o *options) loadHistory() *reporter.History {
+	if o.history == "" {
+		return nil
+	}
+	history, err := reporter.LoadHistory(o.history)
+	if err != nil {
+		o.logger.Warnf("starting a new history: %v", err)
+		history = reporter.NewHistory()
+	}
+	return history
+}
+
+// payloads splits the upload into the projects of the config file
+func (o *options) payloads() ([]reporter.RequestPayload, error) {
+	payload, err := o.payload()
+	if err != nil {
+		return nil, err
+	}
+	if len(o.config.Projects) == 0 {
+		return []reporter.RequestPayload{payload}, nil
+	}
+
+	payloads := []reporter.RequestPayload{}
+	for _, project := range o.config.Projects {
+		scoped := payload.ForProject(project)
+		if !scoped.HasReports() {
+			o.logger.Warnf("no reports found for project %s, skipping", project.Path)
+			continue
+		}
+		payloads = append(payloads, scoped)
+	}
+	if len(payloads) == 0 {
+		return nil, fmt.Errorf("%w for any project in %s", reporter.ErrNoReports, o.configFile)
+	}
+	return payloads, nil
+}
+
+// reports reads the report files of every project into one payload, for
+// the commands that only look at reports
+func (o *options) reports() reporter.RequestPayload {
+	all, err := o.payload()
+	if err != nil {
+		o.fatal(err)
+	}
+	all.RequestData.Filenames = []string{}
+
+	payloads, err := o.payloads()
+	if err != nil {
+		o.fatal(err)
+	}
+	for _, payload := range payloads {
+		if err := payload.GetRunData(); err != nil {
+			o.fatal(err)
+		}
+		all.RequestData.Filenames = append(all.RequestData.Filenames, payload.RequestData.Filenames...)
+		all.RequestData.RunData = append(all.RequestData.RunData, payload.RequestData.RunData...)
+	}
+	return all
+}
+
+func (o *options) isFlagSet(name string) bool {
+	set := false
+	o.flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// flagFields maps flags to the RequestData fields they set
+var flagFields = map[string]string{
+	"host":         "hostname",
+	"branch":       "branch",
+	"sha":          "sha",
+	"tag":          "tag",
+	"pr":           "pr",
+	"slug":         "slug",
+	"ciName":       "ci_name",
+	"buildnumber":  "build_number",
+	"buildurl":     "build_url",
+	"job":          "job",
+	"shardIndex":   "shard_index",
+	"shardTotal":   "shard_total",
+	"retryAttempt": "retry_attempt",
+}
+
+func (o *options) mapFlags() map[string]string {
+	flags := map[string]string{}
+	o.flags.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if value != "" {
+			flags[f.Name] = value
+		}
+	})
+	return flags
+}
+
+// listFlag collects every use of a repeatable flag
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+func (l *listFlag) Get() any       { return []string(*l) }
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
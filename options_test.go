@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/config"
+)
+
+// flagsWithoutEnv only make sense on the command line
+var flagsWithoutEnv = map[string]bool{"version": true, "format": true, "o": true}
+
+// legacyEnvs predate the TR_<FLAG> naming
+var legacyEnvs = map[string]string{
+	"endpoint":    "TR_SITE",
+	"buildnumber": "TR_BUILD_NUMBER",
+	"buildurl":    "TR_BUILD_URL",
+}
+
+func envName(flagName string) string {
+	if env, found := legacyEnvs[flagName]; found {
+		return env
+	}
+	name := "TR_"
+	for _, r := range flagName {
+		if unicode.IsUpper(r) {
+			name += "_"
+		}
+		name += string(unicode.ToUpper(r))
+	}
+	return name
+}
+
+func TestFlagEnvs(t *testing.T) {
+	used := map[string]bool{}
+	for _, cmd := range commands {
+		fs := newFlagSet(&options{}, cmd)
+		fs.VisitAll(func(f *flag.Flag) {
+			env, found := flagEnvs[f.Name]
+			if flagsWithoutEnv[f.Name] {
+				assert.False(t, found, f.Name)
+				return
+			}
+			used[f.Name] = true
+			if assert.True(t, found, "%s -%s has no env var", cmd.name, f.Name) {
+				assert.Equal(t, envName(f.Name), env)
+				assert.True(t, strings.HasSuffix(f.Usage, " (env "+env+")"), f.Usage)
+			}
+		})
+	}
+
+	for name := range flagEnvs {
+		assert.True(t, used[name], "%s is not a flag of any command", name)
+	}
+}
+
+func TestEnvOverridesConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testrecall.yml")
+	require.NoError(t, os.WriteFile(path, []byte("max_failures: 3\nfail_on: failures\nbranch: from-config\n"), 0644))
+	t.Setenv("TR_MAX_FAILURES", "5")
+	t.Setenv("TR_BRANCH", "from-env")
+
+	o := &options{}
+	fs := newFlagSet(o, commands[0])
+	_, err := parse(fs, []string{"-config", path, "-branch", "from-flag"}, false)
+	require.NoError(t, err)
+	o.load(fs)
+
+	assert.Equal(t, 5, o.maxFailures)
+	assert.Equal(t, "failures", o.failOn)
+	assert.Equal(t, "from-flag", o.gitBranch)
+	assert.Equal(t, config.Source{Kind: config.SourceEnv, Name: "TR_MAX_FAILURES"}, o.sources["maxFailures"])
+	assert.Equal(t, config.Source{Kind: config.SourceConfig, Name: path}, o.sources["failOn"])
+	assert.Equal(t, config.Source{Kind: config.SourceFlag, Name: "-branch"}, o.sources["branch"])
+}
@@ -0,0 +1,206 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/testrecall/reporter/git"
+)
+
+var (
+	branchCommand    = strings.Fields("git log -n 1 --pretty=%D HEAD")
+	remotesCommand   = strings.Fields("git remote")
+	nameRevCommand   = strings.Fields("git name-rev --name-only --refs=refs/heads/* --refs=refs/remotes/* HEAD")
+	containsCommand  = strings.Fields("git for-each-ref --contains HEAD --format=%(refname) refs/heads refs/remotes")
+	defaultRemotes   = []string{"origin"}
+	nameRevSuffix    = regexp.MustCompile(`([~^][0-9]*)+$`)
+	branchEnvVars    = []string{"GIT_BRANCH", "BRANCH_NAME", "CI_BRANCH"}
+	detachedBranches = map[string]bool{"": true, "HEAD": true, "undefined": true}
+)
+
+// gitBranch returns the current branch and the command or file it was read
+// from. CI usually checks out a detached HEAD, so after the decorations of
+// HEAD it tries the nearest branch by name-rev and then any branch containing
+// HEAD. Without a working git binary the .git directory is read directly.
+func gitBranch(logger *logrus.Logger) (string, string, error) {
+	// NOTE: ci may be in a detached head
+	out, err := runGit(branchCommand)
+	logger.Debugln("branch: ", out)
+	if err != nil {
+		logger.Debugln("git error checking for detached head, reading .git instead: ", err)
+		return nativeBranch()
+	}
+
+	remotes := gitRemotes()
+	if branch := BranchFromDecorations(out, remotes); branch != "" {
+		return branch, strings.Join(branchCommand, " "), nil
+	}
+
+	if out, err := runGit(nameRevCommand); err == nil {
+		logger.Debugln("name-rev: ", out)
+		if branch := BranchFromNameRev(out, remotes); branch != "" {
+			return branch, strings.Join(nameRevCommand, " "), nil
+		}
+	}
+
+	if out, err := runGit(containsCommand); err == nil {
+		logger.Debugln("contains: ", out)
+		if branch := BranchFromRefs(strings.Fields(out)); branch != "" {
+			return branch, strings.Join(containsCommand, " "), nil
+		}
+	}
+
+	return "", "", nil
+}
+
+func nativeBranch() (string, string, error) {
+	repo, err := git.Open(".")
+	if err != nil {
+		return "", "", errors.New("-branch is a required field, git is unavailable and " + err.Error())
+	}
+	branch, err := repo.Branch()
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read branch from %s: %w", repo.GitDir, err)
+	}
+	return branch, filepath.Join(repo.GitDir, "HEAD"), nil
+}
+
+// envBranch is the last resort, for checkouts without any branch refs. The
+// bare BRANCH is not read, shells and tools set it for their own use.
+func envBranch() (string, string) {
+	for _, env := range branchEnvVars {
+		if branch := NormalizeBranch(os.Getenv(env), defaultRemotes); branch != "" {
+			return branch, env
+		}
+	}
+	return "", ""
+}
+
+func gitRemotes() []string {
+	out, err := runGit(remotesCommand)
+	if err != nil || strings.TrimSpace(out) == "" {
+		return defaultRemotes
+	}
+	return strings.Fields(out)
+}
+
+// GitBranchFromInfo picks a branch from `git log --pretty=%D` output,
+// assuming the only remote is origin
+func GitBranchFromInfo(info string) string {
+	return BranchFromDecorations(info, defaultRemotes)
+}
+
+// BranchFromDecorations picks a branch from `git log --pretty=%D` output.
+// The branch HEAD points to wins, then local branches, then remote-tracking
+// branches with the remote stripped. Tags and symbolic refs are ignored.
+func BranchFromDecorations(info string, remotes []string) string {
+	info = strings.TrimSpace(info)
+	info = strings.TrimSuffix(strings.TrimPrefix(info, "("), ")")
+
+	var locals, remoteBranches []string
+	for _, decoration := range strings.Split(info, ",") {
+		decoration = strings.TrimSpace(decoration)
+
+		if _, branch, found := strings.Cut(decoration, "->"); found {
+			return NormalizeBranch(branch, remotes)
+		}
+		if strings.HasPrefix(decoration, "tag:") || decoration == "grafted" || strings.HasSuffix(decoration, "/HEAD") {
+			continue
+		}
+		if detachedBranches[decoration] || isOtherRef(decoration) {
+			continue
+		}
+
+		if remoteName(decoration, remotes) != "" {
+			remoteBranches = append(remoteBranches, NormalizeBranch(decoration, remotes))
+		} else {
+			locals = append(locals, NormalizeBranch(decoration, remotes))
+		}
+	}
+
+	if len(locals) > 0 {
+		return locals[0]
+	}
+	if len(remoteBranches) > 0 {
+		return remoteBranches[0]
+	}
+	return ""
+}
+
+// BranchFromNameRev parses `git name-rev --name-only` output such as
+// `remotes/origin/main~2`
+func BranchFromNameRev(out string, remotes []string) string {
+	name := nameRevSuffix.ReplaceAllString(strings.TrimSpace(out), "")
+	name = strings.TrimPrefix(name, "remotes/")
+	if detachedBranches[name] || strings.HasPrefix(name, "tags/") {
+		return ""
+	}
+	return NormalizeBranch(name, remotes)
+}
+
+// BranchFromRefs picks a branch from full ref names, preferring local
+// branches and, between equals, the shortest name
+func BranchFromRefs(refs []string) string {
+	var locals, remoteBranches []string
+	for _, ref := range refs {
+		switch {
+		case strings.HasSuffix(ref, "/HEAD"):
+		case strings.HasPrefix(ref, "refs/heads/"):
+			locals = append(locals, strings.TrimPrefix(ref, "refs/heads/"))
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			remote := strings.TrimPrefix(ref, "refs/remotes/")
+			if _, branch, found := strings.Cut(remote, "/"); found {
+				remoteBranches = append(remoteBranches, branch)
+			}
+		}
+	}
+
+	for _, branches := range [][]string{locals, remoteBranches} {
+		if len(branches) == 0 {
+			continue
+		}
+		sort.SliceStable(branches, func(i, j int) bool {
+			if len(branches[i]) != len(branches[j]) {
+				return len(branches[i]) < len(branches[j])
+			}
+			return branches[i] < branches[j]
+		})
+		return branches[0]
+	}
+	return ""
+}
+
+// NormalizeBranch strips ref prefixes and known remotes from a branch name,
+// e.g. refs/heads/main, refs/remotes/origin/main and origin/main are main
+func NormalizeBranch(branch string, remotes []string) string {
+	branch = strings.TrimSpace(branch)
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	branch = strings.TrimPrefix(branch, "refs/remotes/")
+
+	if remote := remoteName(branch, remotes); remote != "" {
+		branch = strings.TrimPrefix(branch, remote+"/")
+	}
+	return branch
+}
+
+// isOtherRef matches full refs that are not branches, e.g. refs/pull/1/head
+func isOtherRef(decoration string) bool {
+	return strings.HasPrefix(decoration, "refs/") &&
+		!strings.HasPrefix(decoration, "refs/heads/") &&
+		!strings.HasPrefix(decoration, "refs/remotes/")
+}
+
+func remoteName(branch string, remotes []string) string {
+	for _, remote := range remotes {
+		if strings.HasPrefix(branch, remote+"/") {
+			return remote
+		}
+	}
+	return ""
+}
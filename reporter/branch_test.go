@@ -0,0 +1,126 @@
+package reporter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestBranchFromDecorations(t *testing.T) {
+	remotes := []string{"origin", "upstream"}
+
+	for _, tt := range []struct {
+		line string
+		want string
+	}{
+		// attached HEAD
+		{line: "HEAD -> master", want: "master"},
+		{line: "HEAD -> feature/login", want: "feature/login"},
+		{line: "(HEAD -> master, origin/master, origin/HEAD)", want: "master"},
+		{line: "HEAD -> main, tag: v1.2.0, origin/main", want: "main"},
+		{line: "origin/main, HEAD -> release", want: "release"},
+		{line: "HEAD -> main\n", want: "main"},
+
+		// detached HEAD, local branches win
+		{line: "HEAD, master", want: "master"},
+		{line: "HEAD, origin/master, master", want: "master"},
+		{line: "HEAD, tag: v1.2, main", want: "main"},
+		{line: "HEAD, main, tag: v1.2", want: "main"},
+		{line: "HEAD, origin/feature, feature", want: "feature"},
+		{line: "HEAD, upstream/main, fork-branch", want: "fork-branch"},
+		{line: "HEAD, feature/origin/nested", want: "feature/origin/nested"},
+
+		// detached HEAD, remote-tracking only
+		{line: "HEAD, origin/feature", want: "feature"},
+		{line: "HEAD, origin/feature/deep/name", want: "feature/deep/name"},
+		{line: "HEAD, upstream/release-1.x", want: "release-1.x"},
+		{line: "HEAD, origin/HEAD, origin/main", want: "main"},
+		{line: "HEAD, tag: v1.2, origin/feature", want: "feature"},
+		{line: "grafted, HEAD, origin/main", want: "main"},
+		{line: "HEAD, refs/pull/12/merge, origin/pr-branch", want: "pr-branch"},
+
+		// nothing usable
+		{line: "", want: ""},
+		{line: "\n", want: ""},
+		{line: "HEAD", want: ""},
+		{line: "HEAD, tag: v1.2", want: ""},
+		{line: "HEAD, tag: v1.2, tag: latest", want: ""},
+		{line: "grafted, HEAD", want: ""},
+		{line: "HEAD, origin/HEAD", want: ""},
+		{line: "HEAD, refs/pull/12/merge", want: ""},
+	} {
+		got := reporter.BranchFromDecorations(tt.line, remotes)
+
+		assert.Equal(t, tt.want, got, tt.line)
+	}
+}
+
+func TestBranchFromNameRev(t *testing.T) {
+	remotes := []string{"origin"}
+
+	for _, tt := range []struct {
+		out  string
+		want string
+	}{
+		{out: "master\n", want: "master"},
+		{out: "master~2\n", want: "master"},
+		{out: "master~2^2~1\n", want: "master"},
+		{out: "feature/x^0\n", want: "feature/x"},
+		{out: "remotes/origin/main\n", want: "main"},
+		{out: "remotes/origin/feature/deep~12\n", want: "feature/deep"},
+		{out: "undefined\n", want: ""},
+		{out: "tags/v1.0~1\n", want: ""},
+		{out: "", want: ""},
+	} {
+		got := reporter.BranchFromNameRev(tt.out, remotes)
+
+		assert.Equal(t, tt.want, got, tt.out)
+	}
+}
+
+func TestBranchFromRefs(t *testing.T) {
+	for _, tt := range []struct {
+		refs []string
+		want string
+	}{
+		{refs: []string{"refs/heads/main"}, want: "main"},
+		{refs: []string{"refs/remotes/origin/main", "refs/heads/feature"}, want: "feature"},
+		{refs: []string{"refs/remotes/origin/HEAD", "refs/remotes/origin/main"}, want: "main"},
+		{refs: []string{"refs/heads/release-1.x", "refs/heads/main"}, want: "main"},
+		{refs: []string{"refs/heads/b", "refs/heads/a"}, want: "a"},
+		{refs: []string{"refs/remotes/upstream/feature/x"}, want: "feature/x"},
+		{refs: []string{"refs/remotes/origin/HEAD"}, want: ""},
+		{refs: []string{"refs/tags/v1.0"}, want: ""},
+		{refs: []string{}, want: ""},
+	} {
+		got := reporter.BranchFromRefs(tt.refs)
+
+		assert.Equal(t, tt.want, got, tt.refs)
+	}
+}
+
+func TestNormalizeBranch(t *testing.T) {
+	remotes := []string{"origin"}
+
+	for _, tt := range []struct {
+		branch string
+		want   string
+	}{
+		{branch: "main", want: "main"},
+		{branch: " main\n", want: "main"},
+		{branch: "refs/heads/main", want: "main"},
+		{branch: "refs/remotes/origin/main", want: "main"},
+		{branch: "origin/main", want: "main"},
+		{branch: "origin/feature/x", want: "feature/x"},
+		{branch: "upstream/main", want: "upstream/main"},
+		{branch: "originals/main", want: "originals/main"},
+	} {
+		got := reporter.NormalizeBranch(tt.branch, remotes)
+
+		assert.Equal(t, tt.want, got, tt.branch)
+	}
+
+	assert.Equal(t, "origin/main", reporter.NormalizeBranch("origin/main", nil))
+	assert.Equal(t, "main", reporter.NormalizeBranch("refs/heads/main", nil))
+}
@@ -0,0 +1,162 @@
+package reporter
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/testrecall/reporter/config"
+)
+
+const DefaultMaxChangedFiles = 500
+
+const (
+	DiffBaseMergeBase = "merge-base"
+	DiffBaseParent    = "parent"
+)
+
+var (
+	defaultBranchCommand = strings.Fields("git symbolic-ref --short refs/remotes/origin/HEAD")
+	shallowCommand       = strings.Fields("git rev-parse --is-shallow-repository")
+	defaultBaseBranches  = []string{"main", "master"}
+)
+
+// Changes describes what the commit under test changed, for test impact
+// analysis
+type Changes struct {
+	BaseBranch string `json:"base_branch"`
+	MergeBase  string `json:"merge_base"`
+	// DiffBase is what Files were diffed against, the merge-base or the
+	// parent commit when there is no usable merge-base
+	DiffBase  string   `json:"diff_base"`
+	Files     []string `json:"files"`
+	Truncated bool     `json:"truncated"`
+	Shallow   bool     `json:"shallow"`
+}
+
+// GetChanges collects the merge-base with the pull request target branch and
+// the files changed since. Any git failure, e.g. history missing from a
+// shallow clone, leaves what could be found and is only logged.
+func (r *RequestPayload) GetChanges() {
+	if !r.ChangedFiles {
+		return
+	}
+
+	changes := &Changes{BaseBranch: r.baseBranch()}
+	r.RequestData.Changes = changes
+
+	if out, err := runGit(shallowCommand); err == nil {
+		changes.Shallow = strings.TrimSpace(out) == "true"
+	} else {
+		r.Logger.Debugln("unable to collect changed files: ", err)
+		return
+	}
+
+	if err := r.diffMergeBase(changes); err != nil {
+		r.Logger.Debugln("no merge-base, diffing against the parent commit: ", err)
+		if err := r.diffParent(changes); err != nil {
+			r.Logger.Debugln("unable to collect changed files: ", err)
+			return
+		}
+	}
+
+	changes.Files = r.inProject(changes.Files)
+
+	limit := r.MaxChangedFiles
+	if limit <= 0 {
+		limit = DefaultMaxChangedFiles
+	}
+	if len(changes.Files) > limit {
+		changes.Files = changes.Files[:limit]
+		changes.Truncated = true
+	}
+}
+
+// baseBranch is the flag, the pull request target from the vendor, or the
+// default branch of origin
+func (r *RequestPayload) baseBranch() string {
+	if r.BaseBranch != "" {
+		r.SetSource("base_branch", config.Source{Kind: config.SourceFlag, Name: "-baseBranch"})
+		return r.BaseBranch
+	}
+
+	if r.isVendorKnown() {
+		if branch := r.fromVendor("base_branch", r.Vendor.GetBaseBranch()); branch != "" {
+			return NormalizeBranch(branch, nil)
+		}
+	}
+
+	if out, err := runGit(defaultBranchCommand); err == nil {
+		r.SetSource("base_branch", config.Source{Kind: config.SourceGit, Name: strings.Join(defaultBranchCommand, " ")})
+		return NormalizeBranch(out, defaultRemotes)
+	}
+
+	for _, branch := range defaultBaseBranches {
+		command := []string{"git", "rev-parse", "--verify", "-q", branch}
+		if _, err := runGit(command); err == nil {
+			r.SetSource("base_branch", config.Source{Kind: config.SourceGit, Name: strings.Join(command, " ")})
+			return branch
+		}
+	}
+	return ""
+}
+
+func (r *RequestPayload) diffMergeBase(changes *Changes) error {
+	if changes.BaseBranch == "" {
+		return errors.New("unknown base branch")
+	}
+
+	var err error
+	for _, ref := range []string{"origin/" + changes.BaseBranch, changes.BaseBranch} {
+		var out string
+		if out, err = runGit([]string{"git", "merge-base", "HEAD", ref}); err != nil {
+			continue
+		}
+		changes.MergeBase = strings.TrimSpace(out)
+		break
+	}
+	if changes.MergeBase == "" {
+		return err
+	}
+
+	// building the base branch itself, the merge-base is HEAD
+	head, err := runGit(shaCommand)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(head) == changes.MergeBase {
+		return errors.New("HEAD is the merge-base")
+	}
+
+	changes.Files, err = diffNames(changes.MergeBase, "HEAD")
+	changes.DiffBase = DiffBaseMergeBase
+	return err
+}
+
+func (r *RequestPayload) diffParent(changes *Changes) error {
+	if _, err := runGit([]string{"git", "rev-parse", "--verify", "-q", "HEAD^"}); err != nil {
+		return errors.New("parent commit is not available, the clone may be too shallow")
+	}
+
+	files, err := diffNames("HEAD^", "HEAD")
+	if err != nil {
+		return err
+	}
+	changes.Files = files
+	changes.DiffBase = DiffBaseParent
+	return nil
+}
+
+func diffNames(from, to string) ([]string, error) {
+	out, err := runGit([]string{"git", "diff", "--name-only", "--no-renames", "-z", from, to})
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,101 @@
+package reporter
+
+import (
+	"strings"
+
+	"github.com/testrecall/reporter/config"
+)
+
+// Commit describes the commit under test
+type Commit struct {
+	AuthorName     string   `json:"author_name"`
+	AuthorEmail    string   `json:"author_email,omitempty"`
+	CommitterName  string   `json:"committer_name"`
+	CommitterEmail string   `json:"committer_email,omitempty"`
+	AuthoredAt     string   `json:"authored_at"`
+	CommittedAt    string   `json:"committed_at"`
+	Subject        string   `json:"subject"`
+	Parents        []string `json:"parents"`
+}
+
+// IsMerge reports whether the commit has more than one parent
+func (c Commit) IsMerge() bool {
+	return len(c.Parents) > 1
+}
+
+// one field per line, the subject is last as it is the only free text
+var commitFormat = strings.Join([]string{"%an", "%ae", "%cn", "%ce", "%aI", "%cI", "%P", "%s"}, "%n")
+
+func (r *RequestPayload) GetCommit() {
+	c := &r.RequestData.Commit
+
+	if r.isVendorKnown() {
+		c.AuthorName, c.AuthorEmail = parseAuthor(r.fromVendor("commit_author", r.Vendor.GetCommitAuthor()))
+		c.Subject = subject(r.fromVendor("commit_message", r.Vendor.GetCommitMessage()))
+		c.AuthoredAt = r.fromVendor("commit_time", r.Vendor.GetCommitTime())
+	}
+
+	if err := r.gitCommit(); err != nil {
+		r.Logger.Debugln("unable to read commit from git: ", err)
+	}
+
+	if r.OmitEmails {
+		c.AuthorEmail = ""
+		c.CommitterEmail = ""
+	}
+}
+
+// gitCommit fills in whatever the vendor did not provide from git show
+func (r *RequestPayload) gitCommit() error {
+	rev := r.RequestData.SHA
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	command := []string{"git", "show", "-s", "--format=" + commitFormat, rev}
+	out, err := runGit(command)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitN(strings.TrimSuffix(out, "\n"), "\n", 8)
+	if len(lines) < 8 {
+		return errUnexpectedOutput(command, out)
+	}
+
+	c := &r.RequestData.Commit
+	for _, field := range []struct {
+		value *string
+		git   string
+	}{
+		{&c.AuthorName, lines[0]},
+		{&c.AuthorEmail, lines[1]},
+		{&c.CommitterName, lines[2]},
+		{&c.CommitterEmail, lines[3]},
+		{&c.AuthoredAt, lines[4]},
+		{&c.CommittedAt, lines[5]},
+		{&c.Subject, lines[7]},
+	} {
+		if *field.value == "" {
+			*field.value = field.git
+		}
+	}
+	c.Parents = strings.Fields(lines[6])
+
+	r.SetSource("commit", config.Source{Kind: config.SourceGit, Name: strings.Join(command[:3], " ")})
+	return nil
+}
+
+// parseAuthor splits "Name <email>" into its parts
+func parseAuthor(author string) (string, string) {
+	name, email, found := strings.Cut(author, "<")
+	if !found {
+		return strings.TrimSpace(author), ""
+	}
+	return strings.TrimSpace(name), strings.TrimSuffix(strings.TrimSpace(email), ">")
+}
+
+func subject(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,160 @@
+package reporter
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	junit "github.com/joshdk/go-junit"
+)
+
+const (
+	FormatJUnit = "junit"
+	FormatJSON  = "json"
+)
+
+type junitTestsuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestsuite `xml:"testsuite"`
+}
+
+type junitTestsuite struct {
+	Name      string          `xml:"name,attr"`
+	Package   string          `xml:"package,attr,omitempty"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Cases     []junitTestcase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+	SystemErr string          `xml:"system-err,omitempty"`
+}
+
+type junitTestcase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Skipped   *junitMessage `xml:"skipped"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// Convert writes every report file as one document, either JUnit xml with
+// nested suites flattened or the parsed suites as json
+func (r RequestPayload) Convert(out io.Writer, format string) error {
+	suites := []junit.Suite{}
+	for _, summary := range r.Summarize() {
+		if summary.Err != nil {
+			return fmt.Errorf("%s: %w", summary.Filename, summary.Err)
+		}
+		suites = append(suites, summary.Suites...)
+	}
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(suites)
+	case FormatJUnit:
+		return writeJUnit(out, suites)
+	}
+	return errors.New("unknown format " + strconv.Quote(format) + ", expected junit or json")
+}
+
+func writeJUnit(out io.Writer, suites []junit.Suite) error {
+	doc := junitTestsuites{}
+	total := junit.Totals{}
+	for _, suite := range flattenSuites(suites) {
+		addTotals(&total, suite.Totals)
+		doc.Suites = append(doc.Suites, toJUnitSuite(suite))
+	}
+	doc.Tests, doc.Failures, doc.Errors, doc.Skipped = total.Tests, total.Failed, total.Error, total.Skipped
+	doc.Time = seconds(total.Duration)
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// flattenSuites lists suites with their nested suites after them, each
+// keeping only its own tests
+func flattenSuites(suites []junit.Suite) []junit.Suite {
+	flat := []junit.Suite{}
+	for _, suite := range suites {
+		nested := suite.Suites
+		suite.Suites = nil
+		suite.Aggregate()
+		flat = append(flat, suite)
+		flat = append(flat, flattenSuites(nested)...)
+	}
+	return flat
+}
+
+func toJUnitSuite(suite junit.Suite) junitTestsuite {
+	s := junitTestsuite{
+		Name:      suite.Name,
+		Package:   suite.Package,
+		Tests:     suite.Totals.Tests,
+		Failures:  suite.Totals.Failed,
+		Errors:    suite.Totals.Error,
+		Skipped:   suite.Totals.Skipped,
+		Time:      seconds(suite.Totals.Duration),
+		SystemOut: suite.SystemOut,
+		SystemErr: suite.SystemErr,
+	}
+
+	for _, test := range suite.Tests {
+		c := junitTestcase{
+			Name:      test.Name,
+			Classname: test.Classname,
+			Time:      seconds(test.Duration),
+			SystemOut: test.SystemOut,
+			SystemErr: test.SystemErr,
+		}
+
+		message := &junitMessage{Message: test.Message}
+		var testErr junit.Error
+		if errors.As(test.Error, &testErr) {
+			message = &junitMessage{Message: testErr.Message, Type: testErr.Type, Body: testErr.Body}
+		}
+		switch test.Status {
+		case junit.StatusSkipped:
+			c.Skipped = message
+		case junit.StatusFailed:
+			c.Failure = message
+		case junit.StatusError:
+			c.Error = message
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	return s
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,46 @@
+package reporter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	junit "github.com/joshdk/go-junit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestConvertJUnit(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, fixturesPayload("golang_fail.xml", "rspec_success.xml").Convert(out, reporter.FormatJUnit))
+
+	assert.Contains(t, out.String(), `<testsuites tests="5" failures="1" errors="0" skipped="0"`)
+	assert.Contains(t, out.String(), `<failure message="Failed">register_test.go:26: name: faz got 0, want 10</failure>`)
+
+	// the output is itself a report that converts to the same totals
+	suites, err := junit.Ingest(out.Bytes())
+	require.NoError(t, err)
+	total := 0
+	for _, suite := range suites {
+		total += suite.Totals.Tests
+	}
+	assert.Equal(t, 5, total)
+}
+
+func TestConvertJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, fixturesPayload("golang_success.xml").Convert(out, reporter.FormatJSON))
+
+	suites := []map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &suites))
+	assert.Len(t, suites, 2)
+	assert.Equal(t, "single_failure/m/billing", suites[0]["name"])
+}
+
+func TestConvertErrors(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.EqualError(t, fixturesPayload("hello.txt").Convert(out, reporter.FormatJUnit), "hello.txt: no test suites found")
+	assert.EqualError(t, fixturesPayload("golang_success.xml").Convert(out, "csv"), `unknown format "csv", expected junit or json`)
+}
@@ -0,0 +1,93 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CrashFilename names the synthetic report uploaded when a command run by
+// exec failed without writing one
+const CrashFilename = "testrecall-crash.xml"
+
+// maxTailBytes caps the output kept however long its lines are
+const maxTailBytes = 64 * 1024
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// tailWriter keeps the last lines written to it, plus any unterminated
+// line at the end
+type tailWriter struct {
+	mu       sync.Mutex
+	lines    int
+	newlines int
+	buf      []byte
+}
+
+func newTailWriter(lines int) *tailWriter {
+	return &tailWriter{lines: lines}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lines <= 0 {
+		return len(p), nil
+	}
+
+	w.buf = append(w.buf, p...)
+	w.newlines += bytes.Count(p, []byte("\n"))
+	if len(w.buf) > maxTailBytes {
+		w.buf = w.buf[len(w.buf)-maxTailBytes:]
+		w.newlines = bytes.Count(w.buf, []byte("\n"))
+	}
+	for w.newlines > w.lines {
+		w.buf = w.buf[bytes.IndexByte(w.buf, '\n')+1:]
+		w.newlines--
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return string(w.buf)
+}
+
+// CrashReport is a JUnit report with a single errored test standing in for
+// a command that failed before writing any report, holding the tail of its
+// output so the failure shows up next to test failures
+func CrashReport(run CommandRun) []byte {
+	message := fmt.Sprintf("%s exited with %d before writing a report", strings.Join(run.Args, " "), run.ExitCode)
+	if run.Signal != "" {
+		message = fmt.Sprintf("%s was killed by %s before writing a report", strings.Join(run.Args, " "), run.Signal)
+	}
+	output := ansiEscape.ReplaceAllString(run.Output, "")
+
+	suite := junitTestsuite{
+		Name:   "testrecall",
+		Tests:  1,
+		Errors: 1,
+		Time:   seconds(run.FinishedAt.Sub(run.StartedAt)),
+		Cases: []junitTestcase{{
+			Name:      "run crashed",
+			Classname: run.Args[0],
+			Time:      seconds(run.FinishedAt.Sub(run.StartedAt)),
+			Error:     &junitMessage{Message: message, Type: "crash", Body: output},
+		}},
+	}
+	doc := junitTestsuites{Tests: 1, Errors: 1, Time: suite.Time, Suites: []junitTestsuite{suite}}
+
+	out := &bytes.Buffer{}
+	out.WriteString(xml.Header)
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	// encoding plain structs of strings cannot fail
+	_ = encoder.Encode(doc)
+	out.WriteString("\n")
+	return out.Bytes()
+}
@@ -0,0 +1,43 @@
+package reporter_test
+
+import (
+	"strings"
+	"testing"
+
+	junit "github.com/joshdk/go-junit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestRunCommandOutput(t *testing.T) {
+	run := reporter.RunCommand([]string{"sh", "-c", `for i in $(seq 1 20); do echo line $i; done; printf partial`}, 5, testLogger())
+	assert.Equal(t, "line 16\nline 17\nline 18\nline 19\nline 20\npartial", run.Output)
+
+	run = reporter.RunCommand([]string{"sh", "-c", "echo oops >&2"}, 5, testLogger())
+	assert.Equal(t, "oops\n", run.Output)
+
+	run = reporter.RunCommand([]string{"sh", "-c", "echo hidden"}, 0, testLogger())
+	assert.Equal(t, "", run.Output)
+}
+
+func TestCrashReport(t *testing.T) {
+	run := reporter.RunCommand([]string{"sh", "-c", `echo "\033[31mSegmentation fault\033[0m <core dumped>"; exit 139`}, reporter.DefaultLogTail, testLogger())
+	report := reporter.CrashReport(run)
+
+	suites, err := junit.Ingest(report)
+	require.NoError(t, err)
+	require.Len(t, suites, 1)
+	assert.Equal(t, 1, suites[0].Totals.Error)
+
+	test := suites[0].Tests[0]
+	assert.Equal(t, "run crashed", test.Name)
+	assert.Equal(t, "sh", test.Classname)
+	assert.Equal(t, junit.StatusError, test.Status)
+	assert.True(t, strings.HasSuffix(test.Message, "exited with 139 before writing a report"), test.Message)
+	assert.Equal(t, "Segmentation fault <core dumped>\n", test.Error.(junit.Error).Body)
+
+	run = reporter.RunCommand([]string{"sh", "-c", "kill -KILL $$"}, reporter.DefaultLogTail, testLogger())
+	assert.Contains(t, string(reporter.CrashReport(run)), `message="sh -c kill -KILL $$ was killed by killed before writing a report"`)
+}
@@ -0,0 +1,157 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/spf13/afero"
+)
+
+// Report formats DetectFormat tells apart. Only junit reports are uploaded.
+const (
+	FormatNUnit    = "nunit"
+	FormatXUnit    = "xunit"
+	FormatTRX      = "trx"
+	FormatTAP      = "tap"
+	FormatCucumber = "cucumber"
+)
+
+// sniffBytes is how much of a file is read to detect its format
+const sniffBytes = 64 * 1024
+
+// xmlRoots maps the root element of each xml report format to the format
+var xmlRoots = map[string]string{
+	"testsuites":   FormatJUnit,
+	"testsuite":    FormatJUnit,
+	"test-run":     FormatNUnit,
+	"test-results": FormatNUnit,
+	"assemblies":   FormatXUnit,
+	"assembly":     FormatXUnit,
+	"TestRun":      FormatTRX,
+}
+
+var tapLine = regexp.MustCompile(`^(TAP version \d+|1\.\.\d+|(not )?ok\b)`)
+
+// DetectFormat classifies a report from the start of its content, by its
+// xml root element, its json structure or its TAP lines. It returns "" for
+// content that is not a test report, like a pom.xml.
+func DetectFormat(head []byte) string {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(head)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	switch trimmed[0] {
+	case '<':
+		return xmlRoots[xmlRoot(trimmed)]
+	case '[':
+		if isCucumber(trimmed) {
+			return FormatCucumber
+		}
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	if scanner.Scan() && tapLine.Match(scanner.Bytes()) {
+		return FormatTAP
+	}
+	return ""
+}
+
+// xmlRoot is the name of the first element of an xml document
+func xmlRoot(content []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	// the root is all that is needed, whatever the declared encoding
+	decoder.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// isCucumber reports whether json content is a list of cucumber features,
+// which hold their scenarios in elements
+func isCucumber(content []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for _, want := range []json.Delim{'[', '{'} {
+		if token, err := decoder.Token(); err != nil || token != want {
+			return false
+		}
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if key == "elements" {
+			return true
+		}
+		if err := decoder.Decode(&json.RawMessage{}); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// errEmptyReport is returned for empty files, which may be reports still
+// being written
+var errEmptyReport = errors.New("empty report")
+
+// detectFile detects the format of a report file from its first bytes
+func detectFile(fs afero.Fs, file string) (string, error) {
+	f, err := fs.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, sniffBytes))
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(head)) == 0 {
+		return "", errEmptyReport
+	}
+	return DetectFormat(head), nil
+}
+
+// SkippedFile is a file matched by a pattern that is not a junit report
+type SkippedFile struct {
+	File string
+	// Format is the report format detected, "" when it is not a report
+	Format string
+}
+
+func (s SkippedFile) String() string {
+	if s.Format == "" {
+		return fmt.Sprintf("skipping %s, not a test report", s.File)
+	}
+	return fmt.Sprintf("skipping %s, a %s report, only junit reports are uploaded", s.File, s.Format)
+}
+
+// isSkipped sniffs a file matched by a wildcard pattern. Files named
+// outright, and files that cannot be sniffed, are kept to fail loudly when
+// they are read.
+func isSkipped(fs afero.Fs, pattern, file string) (SkippedFile, bool) {
+	if !hasMeta(pattern) {
+		return SkippedFile{}, false
+	}
+	format, err := detectFile(fs, file)
+	if err != nil || format == FormatJUnit {
+		return SkippedFile{}, false
+	}
+	return SkippedFile{File: file, Format: format}, true
+}
@@ -0,0 +1,69 @@
+package reporter_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestDetectFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		format  string
+	}{
+		{"junit suites", string(getFixture("golang_fail.xml")), reporter.FormatJUnit},
+		{"junit suite", string(getFixture("rspec_success.xml")), reporter.FormatJUnit},
+		{"junit with bom and comment", "\xef\xbb\xbf<!-- generated -->\n<testsuite name=\"a\"/>", reporter.FormatJUnit},
+		{"junit in utf-16 declaration", `<?xml version="1.0" encoding="UTF-16"?><testsuites/>`, reporter.FormatJUnit},
+		{"nunit 3", `<?xml version="1.0"?><test-run id="2" testcasecount="1"/>`, reporter.FormatNUnit},
+		{"nunit 2", `<test-results name="a.dll" total="1"/>`, reporter.FormatNUnit},
+		{"xunit", `<assemblies><assembly name="a.dll"/></assemblies>`, reporter.FormatXUnit},
+		{"trx", `<TestRun id="1" xmlns="http://microsoft.com/schemas/VisualStudio/TeamTest/2010"/>`, reporter.FormatTRX},
+		{"tap version", "TAP version 13\n1..2\nok 1 - a\n", reporter.FormatTAP},
+		{"tap plan", "1..1\nnot ok 1 - a\n", reporter.FormatTAP},
+		{"cucumber", `[{"uri": "a.feature", "keyword": "Feature", "description": "{\"elements\"", "elements": [{"keyword": "Scenario"}]}]`, reporter.FormatCucumber},
+		{"pom", `<?xml version="1.0"?><project xmlns="http://maven.apache.org/POM/4.0.0"><testsuite/></project>`, ""},
+		{"json list", `[{"name": "a"}, {"elements": []}]`, ""},
+		{"json object", `{"elements": []}`, ""},
+		{"text", string(getFixture("hello.txt")), ""},
+		{"okay text", "okay then\n", ""},
+		{"broken xml", "<<", ""},
+		{"empty", "  \n", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.format, reporter.DetectFormat([]byte(tt.content)))
+		})
+	}
+}
+
+func TestSearchReportFilesSniffs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for file, content := range map[string]string{
+		"/r/pom.xml":           `<project><modelVersion>4.0.0</modelVersion></project>`,
+		"/r/results.trx":       `<TestRun id="1"/>`,
+		"/r/junit.xml":         `<testsuites/>`,
+		"/r/pending.xml":       ``,
+		"/r/target/nunit.xml":  `<test-run id="2"/>`,
+		"/r/target/TEST-a.xml": `<testsuite name="a"/>`,
+	} {
+		require.NoError(t, afero.WriteFile(fs, filepath.FromSlash(file), []byte(content), 0644))
+	}
+
+	files, err := reporter.SearchReportFiles(fs, filepath.FromSlash("/r/**/*"))
+	require.NoError(t, err)
+	assert.Equal(t, fromSlash([]string{"/r/junit.xml", "/r/pending.xml", "/r/target/TEST-a.xml"}), files)
+
+	// files named outright are never sniffed
+	files, err = reporter.SearchReportFiles(fs, filepath.FromSlash("/r/pom.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, fromSlash([]string{"/r/pom.xml"}), files)
+
+	_, err = reporter.SearchReportFiles(fs, filepath.FromSlash("/r/*.trx"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,226 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/testrecall/reporter/ci"
+	"github.com/testrecall/reporter/config"
+)
+
+const doctorTimeout = 5 * time.Second
+
+// Doctor prints how the reporter sees the current environment: which vendor
+// was detected, where each field comes from, which report files would be
+// uploaded, for each of the projects when there are any, and whether the
+// upload endpoint is reachable. Nothing is uploaded.
+func (r *RequestPayload) Doctor(out io.Writer, remoteURL string, projects ...config.Project) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	r.doctorVendors(w)
+	r.doctorMetadata(w)
+
+	payloads := []RequestPayload{*r}
+	if len(projects) > 0 {
+		payloads = []RequestPayload{}
+		for _, p := range projects {
+			payloads = append(payloads, r.ForProject(p))
+		}
+	}
+	for i := range payloads {
+		payloads[i].doctorFiles(w)
+	}
+	doctorToken(w, payloads)
+	doctorConnectivity(w, remoteURL)
+}
+
+func (r *RequestPayload) doctorVendors(w io.Writer) {
+	fmt.Fprintln(w, "CI vendor:")
+
+	r.GetVendor()
+	for _, vendor := range ci.Vendors() {
+		status := "not set"
+		if vendor.Active() {
+			status = "set"
+		}
+		if r.isVendorKnown() && vendor.GetName() == r.Vendor.GetName() {
+			status += " (matched)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", vendor.GetName(), vendor.GetEnv(), status)
+	}
+
+	if !r.isVendorKnown() {
+		fmt.Fprintln(w, "  no vendor matched, falling back to flags and git")
+		fmt.Fprintln(w)
+		return
+	}
+
+	for _, v := range r.Vendor.EnvVars() {
+		status := "missing"
+		if _, found := os.LookupEnv(v.Name); found {
+			status = "present"
+		}
+		fmt.Fprintf(w, "    %s\t%s\t%s\n", v.Field, v.Name, status)
+	}
+	fmt.Fprintln(w)
+}
+
+func (r *RequestPayload) doctorMetadata(w io.Writer) {
+	fmt.Fprintln(w, "Metadata:")
+
+	errs := map[string]error{
+		"branch": r.GetBranch(),
+		"sha":    r.GetSHA(),
+	}
+	r.GetTag()
+	r.GetSlug()
+	r.GetBuildNumber()
+	r.GetBuildURL()
+	r.GetJob()
+	r.GetShardIndex()
+	r.GetShardTotal()
+	r.GetRetryAttempt()
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"branch", r.RequestData.Branch},
+		{"sha", r.RequestData.SHA},
+		{"tag", r.RequestData.Tag},
+		{"slug", r.RequestData.Slug},
+		{"build_number", r.RequestData.BuildNumber},
+		{"build_url", r.RequestData.BuildURL},
+		{"job", r.RequestData.Job},
+		{"shard_index", r.RequestData.ShardIndex},
+		{"shard_total", r.RequestData.ShardTotal},
+		{"retry_attempt", r.RequestData.RetryAttempt},
+	} {
+		source := "not found"
+		if err := errs[field.name]; err != nil {
+			source = "error: " + err.Error()
+		} else if s, found := r.RequestData.Provenance[field.name]; found {
+			source = s.String()
+		}
+		fmt.Fprintf(w, "  %s\t%s\t(%s)\n", field.name, field.value, source)
+	}
+	fmt.Fprintln(w)
+}
+
+func (r *RequestPayload) doctorFiles(w io.Writer) {
+	if r.Dir == "" {
+		fmt.Fprintln(w, "Report files:")
+	} else {
+		fmt.Fprintf(w, "Report files of %s:\n", r.Dir)
+	}
+
+	// the files upload would send, to tell them apart from those matched by
+	// default patterns that are not searched. Search errors show up below as
+	// patterns that fail or match nothing.
+	fs := afero.NewOsFs()
+	found, skipped, _ := searchReportFiles(fs, r.Dir, r.Files, r.AllDefaults)
+	uploaded := map[string]bool{}
+	for _, file := range found {
+		uploaded[filepath.Clean(file)] = true
+	}
+	skips := map[string]SkippedFile{}
+	for _, skip := range skipped {
+		skips[filepath.Clean(skip.File)] = skip
+	}
+
+	include, exclude := splitPatterns(r.Files)
+	if len(include) == 0 {
+		include = defaultPatterns
+	}
+	for _, pattern := range include {
+		matched, err := glob(fs, inDir(r.Dir, pattern))
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "  %s\terror: %v\n", pattern, err)
+		case len(matched) == 0:
+			fmt.Fprintf(w, "  %s\tno matches\n", pattern)
+		default:
+			lines, sent := []string{}, false
+			for _, file := range matched {
+				skip, isSkip := skips[filepath.Clean(file)]
+				switch {
+				case matchesAny(file, r.Dir, exclude) || r.isExcluded(file):
+					lines = append(lines, fmt.Sprintf("    %s\t(excluded)", file))
+				case isSkip:
+					format := skip.Format
+					if format == "" {
+						format = "not a report"
+					}
+					lines = append(lines, fmt.Sprintf("    %s\t(skipped, %s)", file, format))
+				case !uploaded[filepath.Clean(file)]:
+					lines = append(lines, fmt.Sprintf("    %s\t(not searched)", file))
+				case r.isStale(fs, file):
+					lines = append(lines, fmt.Sprintf("    %s\t(stale)", file))
+				default:
+					lines = append(lines, "    "+file)
+					sent = true
+				}
+			}
+
+			note := ""
+			if sent {
+				note = " (uploaded)"
+			}
+			fmt.Fprintf(w, "  %s\t%d matches%s\n", pattern, len(matched), note)
+			for _, line := range lines {
+				fmt.Fprintln(w, line)
+			}
+		}
+	}
+	for _, pattern := range exclude {
+		fmt.Fprintf(w, "  !%s\texcluded\n", pattern)
+	}
+	if !r.FreshAfter.IsZero() {
+		fmt.Fprintf(w, "  files last modified before %s are stale\n", r.FreshAfter.Format(time.RFC3339))
+	}
+	fmt.Fprintln(w)
+}
+
+// doctorToken reports the upload token of each payload, one per project
+func doctorToken(w io.Writer, payloads []RequestPayload) {
+	fmt.Fprintln(w, "Upload token:")
+
+	for _, p := range payloads {
+		env := p.TokenEnv
+		if env == "" {
+			env = defaultTokenEnv
+		}
+		status := "missing"
+		if os.Getenv(env) != "" {
+			status = "present"
+		}
+		if p.Dir == "" {
+			fmt.Fprintf(w, "  %s\t%s\n", env, status)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\t(%s)\n", env, status, p.Dir)
+	}
+	fmt.Fprintln(w)
+}
+
+func doctorConnectivity(w io.Writer, remoteURL string) {
+	fmt.Fprintln(w, "Connectivity:")
+
+	client := http.Client{Timeout: doctorTimeout}
+	start := time.Now()
+	resp, err := client.Get(remoteURL)
+	if err != nil {
+		fmt.Fprintf(w, "  %s\tunreachable: %v\n", remoteURL, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	fmt.Fprintf(w, "  %s\treachable (status %d, %v)\n", remoteURL, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+}
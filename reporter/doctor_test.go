@@ -0,0 +1,90 @@
+package reporter_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testrecall/reporter/config"
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestDoctor(t *testing.T) {
+	clearEnv(t)
+	setEnv(t, "GITLAB_CI", "true")
+	setEnv(t, "CI_COMMIT_SHA", "sha123")
+	setEnv(t, "CI_JOB_ID", "7")
+
+	s, teardown := testingHTTPClient(http.NotFoundHandler())
+	defer teardown()
+
+	payload := reporter.RequestPayload{
+		Files: []string{"./fixtures/golang_*.xml"},
+		RequestData: reporter.RequestData{
+			Branch: "flagbranch",
+		},
+		Logger: testLogger(),
+	}
+
+	out := new(bytes.Buffer)
+	payload.Doctor(out, s.URL)
+
+	assert.Regexp(t, `Gitlab\s+GITLAB_CI\s+set \(matched\)`, out.String())
+	assert.Regexp(t, `CI_COMMIT_SHA\s+present`, out.String())
+	assert.Regexp(t, `CI_JOB_URL\s+missing`, out.String())
+	assert.Regexp(t, `branch\s+flagbranch\s+\(flag\)`, out.String())
+	assert.Regexp(t, `sha\s+sha123\s+\(env CI_COMMIT_SHA\)`, out.String())
+	assert.Regexp(t, `build_url\s+\(not found\)`, out.String())
+	assert.Regexp(t, `golang_\*\.xml\s+2 matches \(uploaded\)`, out.String())
+	assert.Regexp(t, `TR_UPLOAD_TOKEN\s+missing`, out.String())
+	assert.Regexp(t, `reachable \(status 404`, out.String())
+}
+
+func TestDoctorProjects(t *testing.T) {
+	clearEnv(t)
+	setEnv(t, "WEB_TOKEN", "secret")
+
+	report, err := os.ReadFile("./fixtures/golang_success.xml")
+	require.NoError(t, err)
+	web, api := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(web, "junit-web.xml"), report, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(web, "reports"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(web, "reports", "report-web.xml"), report, 0644))
+
+	payload := reporter.RequestPayload{Logger: testLogger()}
+	out := new(bytes.Buffer)
+	payload.Doctor(out, "http://127.0.0.1:1",
+		config.Project{Path: web, TokenEnv: "WEB_TOKEN"},
+		config.Project{Path: api, TokenEnv: "API_TOKEN"},
+	)
+
+	// upload stops at the first default pattern that matches
+	assert.Regexp(t, `junit\*\.xml\s+1 matches \(uploaded\)\n\s+\S+junit-web\.xml\n`, out.String())
+	assert.Regexp(t, `reports/report\*\.xml\s+1 matches\n\s+\S+report-web\.xml\s+\(not searched\)`, out.String())
+	assert.Regexp(t, `WEB_TOKEN\s+present\s+\(`+regexp.QuoteMeta(web)+`\)`, out.String())
+	assert.Regexp(t, `API_TOKEN\s+missing\s+\(`+regexp.QuoteMeta(api)+`\)`, out.String())
+	assert.NotContains(t, out.String(), "TR_UPLOAD_TOKEN")
+}
+
+// clearEnv empties the environment for the test, restoring it afterwards
+func clearEnv(t *testing.T) {
+	env := os.Environ()
+	os.Clearenv()
+	t.Cleanup(func() {
+		os.Clearenv()
+		for _, kv := range env {
+			k, v, _ := strings.Cut(kv, "=")
+			os.Setenv(k, v)
+		}
+	})
+}
+
+func setEnv(t *testing.T, key, value string) {
+	assert.NoError(t, os.Setenv(key, value))
+}
@@ -0,0 +1,117 @@
+package reporter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// exitNotFound and exitSignal follow the shell's conventions
+	exitNotFound = 127
+	exitSignal   = 128
+)
+
+// DefaultLogTail is how many lines of output are kept for a crash report
+const DefaultLogTail = 100
+
+// outputWaitDelay bounds the wait for output after the command exits, in
+// case it left a background process holding stdout open
+const outputWaitDelay = 5 * time.Second
+
+// forwardedSignals are passed on to the test command rather than stopping
+// the reporter, so its reports can still be uploaded
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// CommandRun records how a test command run by exec went
+type CommandRun struct {
+	Args       []string  `json:"args"`
+	ExitCode   int       `json:"exit_code"`
+	Signal     string    `json:"signal,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	// Interrupted is set when the reporter received a signal while the
+	// command was running
+	Interrupted bool `json:"interrupted"`
+
+	// Output is the tail of the command's stdout and stderr, only uploaded
+	// as part of a crash report
+	Output string `json:"-"`
+}
+
+// RunCommand runs a test command attached to the reporter's stdin, stdout
+// and stderr, forwarding signals to it until it exits, other than those it
+// already received from the terminal, and keeping the last logTail lines of
+// its output. The exit code is the command's, 127 when it
+// could not be started, or 128 plus the signal that killed it.
+func RunCommand(args []string, logTail int, logger *logrus.Logger) CommandRun {
+	run := CommandRun{Args: args, StartedAt: time.Now()}
+
+	tail := newTailWriter(logTail)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, tail)
+	cmd.Stderr = io.MultiWriter(os.Stderr, tail)
+	cmd.WaitDelay = outputWaitDelay
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		logger.Errorf("unable to run %s: %v", args[0], err)
+		run.ExitCode = exitNotFound
+		run.finish(tail)
+		return run
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	for {
+		select {
+		case sig := <-signals:
+			run.Interrupted = true
+			if fromTerminal(sig) {
+				logger.Debugf("%s received %v from the terminal", args[0], sig)
+				continue
+			}
+			logger.Debugf("forwarding %v to %s", sig, args[0])
+			if err := cmd.Process.Signal(sig); err != nil {
+				logger.Debugln("unable to forward signal: ", err)
+			}
+		case err := <-done:
+			run.exited(cmd.ProcessState, err)
+			run.finish(tail)
+			return run
+		}
+	}
+}
+
+func (run *CommandRun) exited(state *os.ProcessState, err error) {
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+		run.ExitCode = 1
+		return
+	}
+
+	run.ExitCode = state.ExitCode()
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		run.ExitCode = exitSignal + int(status.Signal())
+		run.Signal = status.Signal().String()
+	}
+}
+
+func (run *CommandRun) finish(tail *tailWriter) {
+	run.Output = tail.String()
+	run.FinishedAt = time.Now()
+	run.DurationMS = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+}
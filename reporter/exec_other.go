@@ -0,0 +1,10 @@
+//go:build !unix
+
+package reporter
+
+import "os"
+
+// fromTerminal is only detected on unix, elsewhere every signal is forwarded
+func fromTerminal(sig os.Signal) bool {
+	return false
+}
@@ -0,0 +1,32 @@
+package reporter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestRunCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args     []string
+		exitCode int
+		signal   string
+	}{
+		{[]string{"sh", "-c", "exit 0"}, 0, ""},
+		{[]string{"sh", "-c", "exit 3"}, 3, ""},
+		{[]string{"sh", "-c", "kill -TERM $$"}, 143, "terminated"},
+		{[]string{"testrecall-no-such-command"}, 127, ""},
+	} {
+		run := reporter.RunCommand(tt.args, reporter.DefaultLogTail, testLogger())
+		assert.Equal(t, tt.args, run.Args)
+		assert.Equal(t, tt.exitCode, run.ExitCode, tt.args)
+		assert.Equal(t, tt.signal, run.Signal, tt.args)
+		assert.False(t, run.Interrupted)
+		assert.False(t, run.FinishedAt.Before(run.StartedAt))
+	}
+
+	run := reporter.RunCommand([]string{"sleep", "0.1"}, 0, testLogger())
+	assert.GreaterOrEqual(t, run.DurationMS, int64(100))
+}
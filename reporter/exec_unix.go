@@ -0,0 +1,32 @@
+//go:build unix
+
+package reporter
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// fromTerminal reports whether sig was typed at the terminal the reporter
+// runs in the foreground of. The terminal sends it to the whole foreground
+// process group, which the test command shares, so forwarding it would
+// deliver it twice, and many runners abort on a second interrupt.
+func fromTerminal(sig os.Signal) bool {
+	if sig != os.Interrupt && sig != syscall.SIGQUIT {
+		return false
+	}
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	foreground, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP)
+	if err != nil {
+		return false
+	}
+	return foreground == unix.Getpgrp()
+}
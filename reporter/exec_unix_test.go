@@ -0,0 +1,28 @@
+//go:build unix
+
+package reporter_test
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestRunCommandForwardsSignals(t *testing.T) {
+	// without a terminal nothing else delivers the signal, so it is
+	// forwarded, once
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+	}()
+	run := reporter.RunCommand([]string{"sh", "-c", `trap 'echo interrupted; exit 3' INT; while true; do sleep 0.05; done`}, reporter.DefaultLogTail, testLogger())
+
+	assert.True(t, run.Interrupted)
+	assert.Equal(t, 3, run.ExitCode)
+	assert.Equal(t, 1, strings.Count(run.Output, "interrupted"), run.Output)
+}
@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// mtimeSlack allows for filesystems that keep modification times in whole
+// seconds
+const mtimeSlack = time.Second
+
+// freshFiles splits report files into those modified since FreshAfter and
+// the stale ones left over from earlier runs
+func (r RequestPayload) freshFiles(fs afero.Fs, files []string) (fresh, stale []string) {
+	fresh = []string{}
+	for _, file := range files {
+		if r.isStale(fs, file) {
+			stale = append(stale, file)
+			continue
+		}
+		fresh = append(fresh, file)
+	}
+	return fresh, stale
+}
+
+func (r RequestPayload) isStale(fs afero.Fs, file string) bool {
+	if r.FreshAfter.IsZero() {
+		return false
+	}
+	info, err := fs.Stat(file)
+	if err != nil {
+		// left to fail when the file is read
+		return false
+	}
+	return info.ModTime().Before(r.FreshAfter.Add(-mtimeSlack))
+}
+
+func staleMessage(stale []string, freshAfter time.Time) string {
+	return fmt.Sprintf("skipping %d stale report files modified before %s: %s",
+		len(stale), freshAfter.Format(time.RFC3339), strings.Join(stale, ", "))
+}
@@ -0,0 +1,58 @@
+package reporter_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestFreshAfter(t *testing.T) {
+	dir := t.TempDir()
+	started := time.Now()
+	fresh := filepath.Join(dir, "junit-fresh.xml")
+	stale := filepath.Join(dir, "junit-stale.xml")
+	require.NoError(t, os.WriteFile(fresh, getFixture("golang_success.xml"), 0644))
+	require.NoError(t, os.WriteFile(stale, getFixture("golang_fail.xml"), 0644))
+	yesterday := started.Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(stale, yesterday, yesterday))
+
+	logger := testLogger()
+	hook := test.NewLocal(logger)
+	payload := reporter.RequestPayload{
+		Files:      []string{filepath.Join(dir, "junit-*.xml")},
+		FreshAfter: started,
+		Logger:     logger,
+	}
+	assert.True(t, payload.HasReports())
+
+	require.NoError(t, payload.GetRunData())
+	assert.Equal(t, []string{fresh}, payload.RequestData.Filenames)
+
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, "skipping 1 stale report files modified before ")
+	assert.Contains(t, hook.LastEntry().Message, stale)
+
+	out := &bytes.Buffer{}
+	payload.Files = []string{stale}
+	payload.Doctor(out, "http://127.0.0.1:1")
+	assert.Contains(t, out.String(), stale+"  (stale)")
+	assert.False(t, payload.HasReports())
+
+	payload.RequestData.Filenames, payload.RequestData.RunData = nil, nil
+	assert.ErrorIs(t, payload.GetRunData(), reporter.ErrNoReports)
+
+	// without a cutoff every file is fresh
+	payload = reporter.RequestPayload{Files: []string{filepath.Join(dir, "junit-*.xml")}, Logger: testLogger()}
+	require.NoError(t, payload.GetRunData())
+	assert.Equal(t, []string{fresh, stale}, payload.RequestData.Filenames)
+}
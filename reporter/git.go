@@ -0,0 +1,107 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/testrecall/reporter/git"
+)
+
+var shaCommand = strings.Fields("git rev-parse HEAD")
+var tagCommand = strings.Fields("git tag --points-at HEAD --sort=-v:refname")
+var describeCommand = strings.Fields("git describe --tags --always")
+
+// runGit runs a git command, returning an error if git is not installed.
+// Only stdout is returned, stderr is kept for the error, so warnings git
+// prints cannot be mistaken for output.
+func runGit(command []string) (string, error) {
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return "", err
+	}
+
+	out, err := exec.Command(command[0], command[1:]...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+func errUnexpectedOutput(command []string, out string) error {
+	return fmt.Errorf("unexpected output from %s: %q", strings.Join(command, " "), out)
+}
+
+// gitSHA returns the HEAD commit and the command or file it was read from.
+// Without a working git binary the .git directory is read directly.
+func gitSHA(logger *logrus.Logger) (string, string, error) {
+	out, err := runGit(shaCommand)
+	if err == nil {
+		return strings.TrimSuffix(out, "\n"), strings.Join(shaCommand, " "), nil
+	}
+	logger.Debugln("git error using rev-parse, reading .git instead: ", err)
+
+	repo, openErr := git.Open(".")
+	if openErr != nil {
+		return "", "", errors.New("-sha is a required field, git is unavailable and " + openErr.Error())
+	}
+	sha, _, err := repo.Head()
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read HEAD from %s: %w", repo.GitDir, err)
+	}
+	return sha, filepath.Join(repo.GitDir, "HEAD"), nil
+}
+
+// gitTag returns the highest versioned tag pointing at HEAD, if any, and the
+// command or file it was read from
+func gitTag(logger *logrus.Logger) (string, string, error) {
+	out, err := runGit(tagCommand)
+	if err == nil {
+		tag, _, _ := strings.Cut(out, "\n")
+		return strings.TrimSpace(tag), strings.Join(tagCommand, " "), nil
+	}
+	logger.Debugln("git error listing tags, reading .git instead: ", err)
+
+	repo, err := git.Open(".")
+	if err != nil {
+		return "", "", err
+	}
+	sha, _, err := repo.Head()
+	if err != nil {
+		return "", "", err
+	}
+	tags, err := repo.Tags(sha)
+	if err != nil || len(tags) == 0 {
+		return "", "", err
+	}
+	// without version sorting, the last tag is the best guess at the newest
+	return tags[len(tags)-1], filepath.Join(repo.CommonDir, "refs", "tags"), nil
+}
+
+var remoteCommand = strings.Fields("git remote get-url origin")
+
+// gitSlug returns the owner/repo of the origin remote and the command or
+// file it was read from
+func gitSlug(logger *logrus.Logger) (string, string, error) {
+	out, err := runGit(remoteCommand)
+	if err == nil {
+		return git.SlugFromURL(out), strings.Join(remoteCommand, " "), nil
+	}
+	logger.Debugln("git error reading origin, reading .git instead: ", err)
+
+	repo, err := git.Open(".")
+	if err != nil {
+		return "", "", err
+	}
+	remote, err := repo.RemoteURL("origin")
+	if err != nil {
+		return "", "", err
+	}
+	return git.SlugFromURL(remote), filepath.Join(repo.CommonDir, "config"), nil
+}
@@ -0,0 +1,179 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	junit "github.com/joshdk/go-junit"
+)
+
+// Labels given to failed tests from their history
+const (
+	// LabelNew failed for the first time, or after passing steadily
+	LabelNew = "new"
+	// LabelPersistent failed the last time it ran too
+	LabelPersistent = "persistent"
+	// LabelFlaky flips between passing and failing, or passed on the same
+	// commit
+	LabelFlaky = "flaky"
+)
+
+const (
+	historyVersion = 1
+	// historyDepth is how many outcomes are kept for each test
+	historyDepth = 30
+	// flakyFlipRate is the share of consecutive outcomes that differ from
+	// which a test is flaky
+	flakyFlipRate = 0.2
+	// flakyMinFlips keeps a single regression and fix from counting as flaky
+	flakyMinFlips = 3
+)
+
+// History records the outcomes of each test across runs, in a local json
+// file that CI can cache between jobs
+type History struct {
+	Version int                  `json:"version"`
+	Tests   map[string][]Outcome `json:"tests"`
+}
+
+// Outcome is how a test did in one run, oldest first in History
+type Outcome struct {
+	SHA    string `json:"sha,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Failed bool   `json:"failed"`
+}
+
+// NewHistory returns an empty history
+func NewHistory() *History {
+	return &History{Version: historyVersion, Tests: map[string][]Outcome{}}
+}
+
+// LoadHistory reads the history at path, a missing file is an empty history
+func LoadHistory(path string) (*History, error) {
+	history := NewHistory()
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, history); err != nil {
+		return nil, fmt.Errorf("invalid history %s: %w", path, err)
+	}
+	if history.Version != historyVersion {
+		return nil, fmt.Errorf("invalid history %s: unknown version %d", path, history.Version)
+	}
+	if history.Tests == nil {
+		history.Tests = map[string][]Outcome{}
+	}
+	return history, nil
+}
+
+// Save writes the history to path, replacing it whole so an interrupted
+// save cannot leave it truncated
+func (h *History) Save(path string) error {
+	content, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Labels labels each failed and errored test of the reports found by
+// GetRunData from its history, before the run is recorded
+func (h *History) Labels(r RequestPayload) map[string]string {
+	labels := map[string]string{}
+	for _, summary := range r.Summarize() {
+		for _, id := range failedTests(summary.Suites) {
+			labels[id] = h.label(id, r.RequestData.SHA, r.RequestData.Branch)
+		}
+	}
+	return labels
+}
+
+func (h *History) label(id, sha, branch string) string {
+	outcomes := h.Tests[id]
+	if len(outcomes) == 0 {
+		return LabelNew
+	}
+
+	for _, outcome := range outcomes {
+		if sha != "" && outcome.SHA == sha && !outcome.Failed {
+			return LabelFlaky
+		}
+	}
+	outcomes = onBranch(outcomes, branch)
+	if flips := flips(outcomes); flips >= flakyMinFlips && float64(flips)/float64(len(outcomes)-1) >= flakyFlipRate {
+		return LabelFlaky
+	}
+	if outcomes[len(outcomes)-1].Failed {
+		return LabelPersistent
+	}
+	return LabelNew
+}
+
+// onBranch keeps the outcomes on branch, or all of them when the test has
+// not run on it before
+func onBranch(outcomes []Outcome, branch string) []Outcome {
+	kept := []Outcome{}
+	for _, outcome := range outcomes {
+		if outcome.Branch == branch {
+			kept = append(kept, outcome)
+		}
+	}
+	if branch == "" || len(kept) == 0 {
+		return outcomes
+	}
+	return kept
+}
+
+// flips counts the consecutive outcomes that differ
+func flips(outcomes []Outcome) int {
+	n := 0
+	for i := 1; i < len(outcomes); i++ {
+		if outcomes[i].Failed != outcomes[i-1].Failed {
+			n++
+		}
+	}
+	return n
+}
+
+// Record adds the outcome of every test that passed, failed or errored in
+// the reports found by GetRunData
+func (h *History) Record(r RequestPayload) {
+	for _, summary := range r.Summarize() {
+		for _, id := range testIDs(summary.Suites, junit.StatusPassed) {
+			h.add(id, Outcome{SHA: r.RequestData.SHA, Branch: r.RequestData.Branch})
+		}
+		for _, id := range failedTests(summary.Suites) {
+			h.add(id, Outcome{SHA: r.RequestData.SHA, Branch: r.RequestData.Branch, Failed: true})
+		}
+	}
+}
+
+func (h *History) add(id string, outcome Outcome) {
+	outcomes := append(h.Tests[id], outcome)
+	if len(outcomes) > historyDepth {
+		outcomes = outcomes[len(outcomes)-historyDepth:]
+	}
+	h.Tests[id] = outcomes
+}
@@ -0,0 +1,130 @@
+package reporter_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+// historyRun is a run of the tests s/steady, which always passes, and
+// s/test, which fails when failed is set
+func historyRun(sha, branch string, failed bool) reporter.RequestPayload {
+	failure := ""
+	if failed {
+		failure = `<failure message="boom"/>`
+	}
+	report := fmt.Sprintf(`<testsuite name="s"><testcase name="steady"/><testcase name="test">%s</testcase></testsuite>`, failure)
+
+	payload := fixturesPayload()
+	payload.RequestData.SHA = sha
+	payload.RequestData.Branch = branch
+	payload.RequestData.Filenames = []string{"junit.xml"}
+	payload.RequestData.RunData = [][]byte{[]byte(report)}
+	return payload
+}
+
+func TestHistoryLabels(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		runs  []reporter.RequestPayload
+		label string
+	}{
+		{"no history", nil, reporter.LabelNew},
+		{"after passing", []reporter.RequestPayload{
+			historyRun("1", "main", false),
+			historyRun("2", "main", false),
+		}, reporter.LabelNew},
+		{"failed last time", []reporter.RequestPayload{
+			historyRun("1", "main", false),
+			historyRun("2", "main", true),
+		}, reporter.LabelPersistent},
+		{"passed on the same commit", []reporter.RequestPayload{
+			historyRun("1", "main", true),
+			historyRun("3", "main", false),
+		}, reporter.LabelFlaky},
+		{"flips", []reporter.RequestPayload{
+			historyRun("1", "main", true),
+			historyRun("2", "main", false),
+			historyRun("4", "main", true),
+			historyRun("5", "main", false),
+			historyRun("6", "main", false),
+			historyRun("7", "main", true),
+		}, reporter.LabelFlaky},
+		{"fixed once", []reporter.RequestPayload{
+			historyRun("1", "main", true),
+			historyRun("2", "main", true),
+			historyRun("4", "main", false),
+			historyRun("5", "main", false),
+		}, reporter.LabelNew},
+		{"failing on another branch", []reporter.RequestPayload{
+			historyRun("1", "main", false),
+			historyRun("2", "feature", true),
+		}, reporter.LabelNew},
+		{"first run on the branch", []reporter.RequestPayload{
+			historyRun("1", "main", true),
+		}, reporter.LabelPersistent},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			history := reporter.NewHistory()
+			for _, run := range tt.runs {
+				history.Record(run)
+			}
+
+			labels := history.Labels(historyRun("3", "main", true))
+			assert.Equal(t, map[string]string{"s/test": tt.label}, labels)
+		})
+	}
+}
+
+func TestHistorySave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	history, err := reporter.LoadHistory(path)
+	require.NoError(t, err)
+	assert.Empty(t, history.Tests)
+
+	for i := 0; i < 40; i++ {
+		history.Record(historyRun(fmt.Sprint(i), "main", i%2 == 0))
+	}
+	require.NoError(t, history.Save(path))
+
+	loaded, err := reporter.LoadHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, history, loaded)
+	assert.Len(t, loaded.Tests["s/steady"], 30)
+	assert.Equal(t, reporter.Outcome{SHA: "39", Branch: "main"}, loaded.Tests["s/test"][29])
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = reporter.LoadHistory(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 9}`), 0644))
+	_, err = reporter.LoadHistory(path)
+	assert.EqualError(t, err, "invalid history "+path+": unknown version 9")
+}
+
+func TestSummaryLabels(t *testing.T) {
+	history := reporter.NewHistory()
+	history.Record(historyRun("1", "main", true))
+
+	payload := historyRun("2", "main", true)
+	payload.RequestData.Labels = history.Labels(payload)
+
+	out := &bytes.Buffer{}
+	payload.Summary(out)
+	assert.Contains(t, out.String(), "Failed:\n  s/test (persistent)\n")
+
+	// a summary saved as a baseline still names the tests
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	require.NoError(t, os.WriteFile(path, []byte("  s/test (persistent)\n"), 0644))
+	baseline, err := reporter.LoadBaseline(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"s/test": true}, baseline)
+}
@@ -0,0 +1,144 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	junit "github.com/joshdk/go-junit"
+)
+
+const (
+	// markdownFailures is how many failed tests a job summary lists
+	markdownFailures = 50
+	// markdownMessage is how much of a failure message a job summary shows
+	markdownMessage = 200
+	// markdownSlowest is how many of the slowest tests a job summary lists
+	markdownSlowest = 5
+)
+
+// Markdown writes a job summary of the reports found by GetRunData, for the
+// CI to show with the job: the totals, the failed tests with the start of
+// their message, the slowest tests and a link to the run when runURL is set
+func (r RequestPayload) Markdown(out io.Writer, runURL string) {
+	title := "Test results"
+	if r.Dir != "" {
+		title += " for " + r.Dir
+	}
+	fmt.Fprintf(out, "### %s\n\n", title)
+
+	total := junit.Totals{}
+	failed, retried, invalid := []junit.Test{}, []string{}, []string{}
+	failedIDs, slowest := []string{}, []junit.Test{}
+	for _, summary := range r.Summarize() {
+		if summary.Err != nil {
+			invalid = append(invalid, summary.Filename)
+			continue
+		}
+		addTotals(&total, summary.Totals)
+		retried = append(retried, summary.Retried...)
+		eachTest(summary.Suites, func(suite junit.Suite, test junit.Test) {
+			id := TestID(suite, test)
+			if test.Status == junit.StatusFailed || test.Status == junit.StatusError {
+				failed = append(failed, test)
+				failedIDs = append(failedIDs, id)
+			}
+			if test.Duration > 0 {
+				// the slowest tests are listed by id
+				test.Name = id
+				slowest = append(slowest, test)
+			}
+		})
+	}
+
+	fmt.Fprintln(out, "| tests | passed | failed | errors | skipped | time |")
+	fmt.Fprintln(out, "| ---: | ---: | ---: | ---: | ---: | ---: |")
+	fmt.Fprintf(out, "| %d | %d | %d | %d | %d | %v |\n", total.Tests, total.Passed, total.Failed, total.Error, total.Skipped, total.Duration.Round(time.Millisecond))
+
+	if runURL != "" {
+		fmt.Fprintf(out, "\n[View the run on TestRecall](%s)\n", runURL)
+	}
+
+	if len(invalid) > 0 {
+		fmt.Fprintf(out, "\n%d report files could not be parsed: %s\n", len(invalid), markdownCode(invalid))
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintln(out, "\n#### Failed tests")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "| test | message |")
+		fmt.Fprintln(out, "| --- | --- |")
+		for i, test := range failed {
+			if i == markdownFailures {
+				fmt.Fprintf(out, "\nand %d more\n", len(failed)-markdownFailures)
+				break
+			}
+			name := "`" + markdownCell(failedIDs[i]) + "`"
+			if label := r.RequestData.Labels[failedIDs[i]]; label != "" {
+				name += " (" + label + ")"
+			}
+			fmt.Fprintf(out, "| %s | %s |\n", name, markdownCell(truncate(failureMessage(test), markdownMessage)))
+		}
+	}
+
+	if len(retried) > 0 {
+		fmt.Fprintln(out, "\n#### Flaky tests, passed on a retry")
+		fmt.Fprintln(out)
+		for _, id := range retried {
+			fmt.Fprintf(out, "- `%s`\n", markdownCell(id))
+		}
+	}
+
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	if len(slowest) > markdownSlowest {
+		slowest = slowest[:markdownSlowest]
+	}
+	if len(slowest) > 0 {
+		fmt.Fprintln(out, "\n#### Slowest tests")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "| test | time |")
+		fmt.Fprintln(out, "| --- | ---: |")
+		for _, test := range slowest {
+			fmt.Fprintf(out, "| `%s` | %v |\n", markdownCell(test.Name), test.Duration.Round(time.Millisecond))
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// failureMessage is the first line of a failed test's message, or of its
+// failure body when it has no message
+func failureMessage(test junit.Test) string {
+	message := test.Message
+	if message == "" && test.Error != nil {
+		message = test.Error.Error()
+	}
+	message = strings.TrimSpace(message)
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = message[:i]
+	}
+	return message
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// markdownCell keeps text on one line of a markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r", "", "\n", " ", "`", "'").Replace(s)
+}
+
+func markdownCode(names []string) string {
+	quoted := []string{}
+	for _, name := range names {
+		quoted = append(quoted, "`"+markdownCell(name)+"`")
+	}
+	return strings.Join(quoted, ", ")
+}
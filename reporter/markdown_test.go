@@ -0,0 +1,69 @@
+package reporter_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	payload := fixturesPayload("golang_fail.xml", "pytest_rerun.xml", "rspec_malformed.xml")
+	payload.RequestData.Labels = map[string]string{failedID: "persistent"}
+
+	out := &bytes.Buffer{}
+	payload.Markdown(out, "https://app.testrecall.com/runs/12")
+	assert.Equal(t, "### Test results\n"+`
+| tests | passed | failed | errors | skipped | time |
+| ---: | ---: | ---: | ---: | ---: | ---: |
+| 6 | 4 | 2 | 0 | 0 | 560ms |
+
+[View the run on TestRecall](https://app.testrecall.com/runs/12)
+
+1 report files could not be parsed: `+"`rspec_malformed.xml`"+`
+
+#### Failed tests
+
+| test | message |
+| --- | --- |
+| `+"`single_failure/m/register/register/TestRegister`"+` (persistent) | Failed |
+| `+"`pytest/tests.test_api/test_profile`"+` | AssertionError |
+
+#### Flaky tests, passed on a retry
+
+- `+"`pytest/tests.test_api/test_login`"+`
+
+#### Slowest tests
+
+| test | time |
+| --- | ---: |
+| `+"`pytest/tests.test_api/test_profile`"+` | 300ms |
+| `+"`pytest/tests.test_api/test_login`"+` | 180ms |
+| `+"`pytest/tests.test_api/test_logout`"+` | 50ms |
+| `+"`single_failure/m/register/register/TestRegister`"+` | 20ms |
+| `+"`single_failure/m/billing/billing/TestAddPayment`"+` | 10ms |
+
+`, out.String())
+}
+
+func TestMarkdownTruncates(t *testing.T) {
+	cases := []string{}
+	for i := 0; i < 60; i++ {
+		cases = append(cases, fmt.Sprintf(`<testcase name="t%d"><failure message="%s|x&#10;second line"/></testcase>`, i, strings.Repeat("a", 300)))
+	}
+	payload := fixturesPayload()
+	payload.Dir = "services/api"
+	payload.RequestData.Filenames = []string{"junit.xml"}
+	payload.RequestData.RunData = [][]byte{[]byte(`<testsuite name="s">` + strings.Join(cases, "") + `</testsuite>`)}
+
+	out := &bytes.Buffer{}
+	payload.Markdown(out, "")
+	assert.True(t, strings.HasPrefix(out.String(), "### Test results for services/api\n"))
+	assert.NotContains(t, out.String(), "View the run")
+	assert.NotContains(t, out.String(), "Slowest tests")
+	assert.Contains(t, out.String(), "| `s/t0` | "+strings.Repeat("a", 199)+"… |\n")
+	assert.Contains(t, out.String(), "\nand 10 more\n")
+	assert.NotContains(t, out.String(), "s/t50")
+}
@@ -0,0 +1,242 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	junit "github.com/joshdk/go-junit"
+)
+
+// Exit codes, one per reason a run can fail. 2 is left to usage errors.
+const (
+	ExitOK          = 0
+	ExitFailures    = 1
+	ExitErrors      = 3
+	ExitInvalid     = 4
+	ExitNoTests     = 5
+	ExitUpload      = 6
+	ExitNewFailures = 7
+	// ExitSetup is for a reporter that could not run, e.g. for an invalid
+	// config file, rather than for anything in the reports
+	ExitSetup = 8
+)
+
+// Reasons a run can fail, as listed in -failOn
+const (
+	FailOnFailures    = "failures"
+	FailOnNewFailures = "new-failures"
+	FailOnErrors      = "errors"
+	FailOnInvalid     = "invalid"
+	FailOnNoTests     = "no-tests"
+	FailOnUpload      = "upload"
+)
+
+// DefaultFailOn fails on what the reporter always has: failed tests, reports
+// that cannot be parsed or cannot be found and failed uploads
+var DefaultFailOn = []string{FailOnFailures, FailOnInvalid, FailOnNoTests, FailOnUpload}
+
+// failOnOrder is the order reasons are checked in, the first that applies
+// decides the exit code
+var failOnOrder = []struct {
+	reason string
+	code   int
+}{
+	{FailOnFailures, ExitFailures},
+	{FailOnNewFailures, ExitNewFailures},
+	{FailOnErrors, ExitErrors},
+	{FailOnInvalid, ExitInvalid},
+	{FailOnNoTests, ExitNoTests},
+	{FailOnUpload, ExitUpload},
+}
+
+// Results tallies what the exit policy looks at, across every report
+type Results struct {
+	Tests int
+	// Failed are the ids of the failed tests, as printed by summary
+	Failed []string
+	// Flaky are the ids of the tests that passed on a retry, which are not
+	// failures
+	Flaky []string
+	// Errored are the ids of the tests that errored
+	Errored      []string
+	Invalid      int
+	UploadFailed bool
+	// SetupFailed is set when a payload could not be prepared, e.g. without
+	// its upload token, which fails the run whatever the policy
+	SetupFailed bool
+}
+
+// Add merges the results of another upload
+func (r *Results) Add(other Results) {
+	r.Tests += other.Tests
+	r.Failed = append(r.Failed, other.Failed...)
+	r.Flaky = append(r.Flaky, other.Flaky...)
+	r.Errored = append(r.Errored, other.Errored...)
+	r.Invalid += other.Invalid
+	r.UploadFailed = r.UploadFailed || other.UploadFailed
+	r.SetupFailed = r.SetupFailed || other.SetupFailed
+}
+
+// Results tallies the report files found by GetRunData
+func (r RequestPayload) Results() Results {
+	results := Results{Failed: []string{}, Flaky: []string{}, Errored: []string{}}
+	for _, summary := range r.Summarize() {
+		if summary.Err != nil {
+			results.Invalid++
+			continue
+		}
+		results.Tests += summary.Totals.Tests
+		results.Failed = append(results.Failed, testIDs(summary.Suites, junit.StatusFailed)...)
+		results.Flaky = append(results.Flaky, summary.Retried...)
+		results.Errored = append(results.Errored, testIDs(summary.Suites, junit.StatusError)...)
+	}
+	return results
+}
+
+// Policy decides the exit code of a run from its results
+type Policy struct {
+	FailOn []string
+	// MaxFailures is how many failed tests, or new failures, are tolerated
+	MaxFailures int
+	// Baseline holds the ids of tests known to fail, which are not new
+	// failures
+	Baseline map[string]bool
+	// Quarantine holds the tests whose failures and errors never fail the
+	// run
+	Quarantine Quarantine
+}
+
+// ParseFailOn splits a comma separated list of reasons
+func ParseFailOn(value string) ([]string, error) {
+	reasons := []string{}
+	for _, reason := range strings.Split(value, ",") {
+		reason = strings.TrimSpace(reason)
+		if reason == "" {
+			continue
+		}
+		if !knownReason(reason) {
+			return nil, fmt.Errorf("unknown -failOn reason %q", reason)
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons, nil
+}
+
+func knownReason(reason string) bool {
+	for _, rule := range failOnOrder {
+		if rule.reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns the code of the first reason that applies, and why
+func (p Policy) ExitCode(r Results) (int, string) {
+	if r.SetupFailed {
+		return ExitSetup, "a report could not be prepared for upload"
+	}
+	for _, rule := range failOnOrder {
+		if !p.FailsOn(rule.reason) {
+			continue
+		}
+		if why := p.check(rule.reason, r); why != "" {
+			return rule.code, why
+		}
+	}
+	return ExitOK, ""
+}
+
+// FailsOn reports whether the policy fails the run for reason
+func (p Policy) FailsOn(reason string) bool {
+	for _, r := range p.FailOn {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) check(reason string, r Results) string {
+	failed, errored := p.Quarantine.Without(r.Failed), p.Quarantine.Without(r.Errored)
+	switch reason {
+	case FailOnFailures:
+		if len(failed) > p.MaxFailures {
+			return fmt.Sprintf("%d tests failed", len(failed))
+		}
+	case FailOnNewFailures:
+		if n := len(p.newFailures(failed)); n > p.MaxFailures {
+			return fmt.Sprintf("%d tests failed that are not in the baseline", n)
+		}
+	case FailOnErrors:
+		if len(errored) > 0 {
+			return fmt.Sprintf("%d tests errored", len(errored))
+		}
+	case FailOnInvalid:
+		if r.Invalid > 0 {
+			return fmt.Sprintf("%d report files are invalid", r.Invalid)
+		}
+	case FailOnNoTests:
+		if r.Tests == 0 {
+			return "no tests found"
+		}
+	case FailOnUpload:
+		if r.UploadFailed {
+			return "upload failed"
+		}
+	}
+	return ""
+}
+
+func (p Policy) newFailures(failed []string) []string {
+	fresh := []string{}
+	for _, id := range failed {
+		if !p.Baseline[id] {
+			fresh = append(fresh, id)
+		}
+	}
+	return fresh
+}
+
+// withoutLabel drops the history label summary prints after a failed test
+func withoutLabel(line string) string {
+	for _, label := range []string{LabelNew, LabelPersistent, LabelFlaky} {
+		if trimmed := strings.TrimSuffix(line, " ("+label+")"); trimmed != line {
+			return trimmed
+		}
+	}
+	return line
+}
+
+// LoadBaseline reads the tests known to fail, either the failed tests of a
+// JUnit report or a list of test ids, one per line, as printed by summary
+func LoadBaseline(path string) (map[string]bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := map[string]bool{}
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		suites, err := junit.Ingest(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+		}
+		for _, id := range testIDs(suites, junit.StatusFailed) {
+			baseline[id] = true
+		}
+		return baseline, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			baseline[withoutLabel(line)] = true
+		}
+	}
+	return baseline, scanner.Err()
+}
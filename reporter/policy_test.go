@@ -0,0 +1,172 @@
+package reporter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+const failedID = "single_failure/m/register/register/TestRegister"
+
+func TestResults(t *testing.T) {
+	results := fixturesPayload("golang_fail.xml", "rspec_success.xml", "rspec_malformed.xml").Results()
+	assert.Equal(t, 5, results.Tests)
+	assert.Equal(t, []string{failedID}, results.Failed)
+	assert.Empty(t, results.Errored)
+	assert.Equal(t, 1, results.Invalid)
+
+	results.Add(reporter.Results{Tests: 1, Errored: []string{"b"}, UploadFailed: true})
+	assert.Equal(t, 6, results.Tests)
+	assert.Equal(t, []string{"b"}, results.Errored)
+	assert.True(t, results.UploadFailed)
+}
+
+func TestParseFailOn(t *testing.T) {
+	reasons, err := reporter.ParseFailOn("failures, no-tests,,upload")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"failures", "no-tests", "upload"}, reasons)
+
+	reasons, err = reporter.ParseFailOn("")
+	require.NoError(t, err)
+	assert.Empty(t, reasons)
+
+	_, err = reporter.ParseFailOn("failures,flaky")
+	assert.EqualError(t, err, `unknown -failOn reason "flaky"`)
+}
+
+func TestExitCode(t *testing.T) {
+	everything := []string{"failures", "new-failures", "errors", "invalid", "no-tests", "upload"}
+
+	testCases := []struct {
+		name    string
+		policy  reporter.Policy
+		results reporter.Results
+		code    int
+		why     string
+	}{
+		{
+			name:    "passing",
+			policy:  reporter.Policy{FailOn: everything},
+			results: reporter.Results{Tests: 3},
+			code:    reporter.ExitOK,
+		},
+		{
+			name:    "failures first",
+			policy:  reporter.Policy{FailOn: everything},
+			results: reporter.Results{Tests: 3, Failed: []string{"a"}, Errored: []string{"b"}, UploadFailed: true},
+			code:    reporter.ExitFailures,
+			why:     "1 tests failed",
+		},
+		{
+			name:    "under max failures",
+			policy:  reporter.Policy{FailOn: reporter.DefaultFailOn, MaxFailures: 2},
+			results: reporter.Results{Tests: 3, Failed: []string{"a", "b"}},
+			code:    reporter.ExitOK,
+		},
+		{
+			name:    "over max failures",
+			policy:  reporter.Policy{FailOn: reporter.DefaultFailOn, MaxFailures: 2},
+			results: reporter.Results{Tests: 3, Failed: []string{"a", "b", "c"}},
+			code:    reporter.ExitFailures,
+			why:     "3 tests failed",
+		},
+		{
+			name:    "known failures",
+			policy:  reporter.Policy{FailOn: []string{"new-failures"}, Baseline: map[string]bool{"a": true}},
+			results: reporter.Results{Tests: 3, Failed: []string{"a"}},
+			code:    reporter.ExitOK,
+		},
+		{
+			name:    "new failures",
+			policy:  reporter.Policy{FailOn: []string{"new-failures"}, Baseline: map[string]bool{"a": true}},
+			results: reporter.Results{Tests: 3, Failed: []string{"a", "b"}},
+			code:    reporter.ExitNewFailures,
+			why:     "1 tests failed that are not in the baseline",
+		},
+		{
+			name:    "errors",
+			policy:  reporter.Policy{FailOn: []string{"errors", "upload"}},
+			results: reporter.Results{Tests: 3, Failed: []string{"a"}, Errored: []string{"b", "c"}, UploadFailed: true},
+			code:    reporter.ExitErrors,
+			why:     "2 tests errored",
+		},
+		{
+			name:    "invalid",
+			policy:  reporter.Policy{FailOn: reporter.DefaultFailOn},
+			results: reporter.Results{Tests: 3, Invalid: 1},
+			code:    reporter.ExitInvalid,
+			why:     "1 report files are invalid",
+		},
+		{
+			name:    "no tests",
+			policy:  reporter.Policy{FailOn: everything},
+			results: reporter.Results{},
+			code:    reporter.ExitNoTests,
+			why:     "no tests found",
+		},
+		{
+			name:    "no reports by default",
+			policy:  reporter.Policy{FailOn: reporter.DefaultFailOn},
+			results: reporter.Results{},
+			code:    reporter.ExitNoTests,
+			why:     "no tests found",
+		},
+		{
+			name:    "no tests allowed",
+			policy:  reporter.Policy{FailOn: []string{"failures", "invalid", "upload"}},
+			results: reporter.Results{},
+			code:    reporter.ExitOK,
+		},
+		{
+			name:    "upload",
+			policy:  reporter.Policy{FailOn: reporter.DefaultFailOn},
+			results: reporter.Results{Tests: 3, UploadFailed: true},
+			code:    reporter.ExitUpload,
+			why:     "upload failed",
+		},
+		{
+			name:    "setup failed",
+			policy:  reporter.Policy{FailOn: []string{}},
+			results: reporter.Results{Tests: 3, Failed: []string{"a"}, SetupFailed: true},
+			code:    reporter.ExitSetup,
+			why:     "a report could not be prepared for upload",
+		},
+		{
+			name:    "upload allowed",
+			policy:  reporter.Policy{FailOn: []string{"failures"}},
+			results: reporter.Results{Tests: 3, UploadFailed: true},
+			code:    reporter.ExitOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, why := tc.policy.ExitCode(tc.results)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.why, why)
+		})
+	}
+}
+
+func TestLoadBaseline(t *testing.T) {
+	baseline, err := reporter.LoadBaseline(filepath.Join(fixturesDir, "golang_fail.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{failedID: true}, baseline)
+
+	list := filepath.Join(t.TempDir(), "baseline.txt")
+	require.NoError(t, os.WriteFile(list, []byte("# known failures\n"+failedID+"\n\n  suite/test  \n"), 0o644))
+	baseline, err = reporter.LoadBaseline(list)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{failedID: true, "suite/test": true}, baseline)
+
+	_, err = reporter.LoadBaseline(filepath.Join(fixturesDir, "rspec_malformed.xml"))
+	assert.Error(t, err)
+
+	_, err = reporter.LoadBaseline(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
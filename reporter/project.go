@@ -0,0 +1,67 @@
+package reporter
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/testrecall/reporter/config"
+)
+
+// ForProject copies the payload, scoped to a project. Per-project fields from
+// the config win over the shared ones.
+func (r RequestPayload) ForProject(p config.Project) RequestPayload {
+	scoped := r
+	scoped.Dir = filepath.Clean(p.Path)
+	scoped.RequestData.ProjectPath = filepath.ToSlash(scoped.Dir)
+
+	scoped.RequestData.Flags = map[string]string{}
+	for k, v := range r.RequestData.Flags {
+		scoped.RequestData.Flags[k] = v
+	}
+	scoped.RequestData.Provenance = map[string]config.Source{}
+	for k, v := range r.RequestData.Provenance {
+		scoped.RequestData.Provenance[k] = v
+	}
+	scoped.RequestData.Filenames = nil
+	scoped.RequestData.RunData = [][]byte{}
+
+	if len(p.Files) > 0 {
+		scoped.Files = p.Files
+	}
+	if p.TokenEnv != "" {
+		scoped.TokenEnv = p.TokenEnv
+	}
+	if p.Slug != "" {
+		scoped.RequestData.Slug = p.Slug
+		scoped.SetSource("slug", config.Source{Kind: config.SourceConfig, Name: "projects"})
+	}
+	return scoped
+}
+
+// HasReports reports whether any report files would be found for upload
+func (r RequestPayload) HasReports() bool {
+	fs := afero.NewOsFs()
+	files, err := SearchReportFilesIn(fs, r.Dir, r.Files, r.AllDefaults)
+	if err != nil {
+		return false
+	}
+	fresh, _ := r.freshFiles(fs, r.excludeFiles(files))
+	return len(fresh) > 0
+}
+
+// inProject filters repository-relative paths to those under Dir
+func (r RequestPayload) inProject(files []string) []string {
+	if r.Dir == "" || r.Dir == "." {
+		return files
+	}
+
+	prefix := filepath.ToSlash(r.Dir) + "/"
+	scoped := []string{}
+	for _, file := range files {
+		if strings.HasPrefix(file, prefix) {
+			scoped = append(scoped, file)
+		}
+	}
+	return scoped
+}
@@ -0,0 +1,61 @@
+package reporter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testrecall/reporter/config"
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestForProject(t *testing.T) {
+	shared := reporter.RequestPayload{
+		Files:  []string{"reports/*.xml"},
+		Logger: testLogger(),
+		RequestData: reporter.RequestData{
+			Branch: "main",
+			Slug:   "acme/monorepo",
+			Flags:  map[string]string{"branch": "main"},
+		},
+	}
+	shared.SetSource("branch", config.Source{Kind: config.SourceFlag, Name: "-branch"})
+
+	api := shared.ForProject(config.Project{
+		Path:     "services/api/",
+		Files:    config.Patterns{"build/*.xml"},
+		TokenEnv: "TR_API_TOKEN",
+		Slug:     "acme/api",
+	})
+	web := shared.ForProject(config.Project{Path: "services/web"})
+
+	assert.Equal(t, "services/api", api.Dir)
+	assert.Equal(t, "services/api", api.RequestData.ProjectPath)
+	assert.Equal(t, []string{"build/*.xml"}, api.Files)
+	assert.Equal(t, "TR_API_TOKEN", api.TokenEnv)
+	assert.Equal(t, "acme/api", api.RequestData.Slug)
+	assert.Equal(t, "main", api.RequestData.Branch)
+	assert.Equal(t, config.SourceConfig, api.RequestData.Provenance["slug"].Kind)
+
+	assert.Equal(t, []string{"reports/*.xml"}, web.Files)
+	assert.Equal(t, "", web.TokenEnv)
+	assert.Equal(t, "acme/monorepo", web.RequestData.Slug)
+
+	// projects do not share state
+	api.RequestData.Flags["job"] = "api"
+	assert.NotContains(t, shared.RequestData.Flags, "job")
+	assert.NotContains(t, shared.RequestData.Provenance, "slug")
+}
+
+func TestProjectHasReports(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "api", "reports"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "api", "reports", "junit.xml"), getFixture("golang_success.xml"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "web"), 0755))
+
+	shared := reporter.RequestPayload{Logger: testLogger()}
+	assert.True(t, shared.ForProject(config.Project{Path: filepath.Join(dir, "api")}).HasReports())
+	assert.False(t, shared.ForProject(config.Project{Path: filepath.Join(dir, "web")}).HasReports())
+	assert.True(t, shared.ForProject(config.Project{Path: filepath.Join(dir, "api"), Files: config.Patterns{"reports/*.xml"}}).HasReports())
+}
@@ -0,0 +1,49 @@
+package reporter
+
+import (
+	"sort"
+
+	"github.com/testrecall/reporter/config"
+)
+
+// SetSource records the source of a field, keyed by its json name
+func (r *RequestPayload) SetSource(field string, source config.Source) {
+	if r.RequestData.Provenance == nil {
+		r.RequestData.Provenance = map[string]config.Source{}
+	}
+	r.RequestData.Provenance[field] = source
+}
+
+// preset reports whether a field was already given a value before
+// resolution, which is taken to be an explicit flag unless the caller
+// recorded another source
+func (r *RequestPayload) preset(field, value string) bool {
+	if value == "" {
+		return false
+	}
+	if _, found := r.RequestData.Provenance[field]; !found {
+		r.SetSource(field, config.Source{Kind: config.SourceFlag})
+	}
+	return true
+}
+
+// fromVendor returns the vendor value of a field, recording the env var it
+// was read from
+func (r *RequestPayload) fromVendor(field, value string) string {
+	if value != "" {
+		r.SetSource(field, config.Source{Kind: config.SourceEnv, Name: r.Vendor.Source(field)})
+	}
+	return value
+}
+
+func (r RequestPayload) logProvenance() {
+	fields := make([]string, 0, len(r.RequestData.Provenance))
+	for field := range r.RequestData.Provenance {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		r.Logger.Debugf("%s: from %v", field, r.RequestData.Provenance[field])
+	}
+}
@@ -0,0 +1,163 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// quarantineDate is the layout of a quarantine expiry date
+const quarantineDate = "2006-01-02"
+
+// QuarantineEntry keeps a test, or every test matching a pattern, from
+// failing the run until it expires
+type QuarantineEntry struct {
+	// Test is a test id as printed by summary, matched as a whole so that
+	// * also matches the / in suite and test names, and **/ matches any
+	// number of parts, including none
+	Test string `yaml:"test"`
+	// Owner is who fixes the test
+	Owner string `yaml:"owner"`
+	// Expires is the last day the entry applies, as 2006-01-02
+	Expires string `yaml:"expires"`
+	Reason  string `yaml:"reason"`
+
+	expires time.Time
+}
+
+// Quarantine lists the tests whose failures are reported but do not fail
+// the run
+type Quarantine []QuarantineEntry
+
+// LoadQuarantine reads a yaml list of quarantine entries. Every entry needs a
+// test, an owner and an expiry date, so none is forgotten.
+func LoadQuarantine(path string) (Quarantine, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantine := Quarantine{}
+	if err := yaml.Unmarshal(content, &quarantine); err != nil {
+		return nil, fmt.Errorf("invalid quarantine %s: %w", path, err)
+	}
+	for i := range quarantine {
+		entry := &quarantine[i]
+		if entry.Test == "" || entry.Owner == "" || entry.Expires == "" {
+			return nil, fmt.Errorf("invalid quarantine %s: entry %d needs a test, owner and expires", path, i+1)
+		}
+		if _, err := filepath.Match(entry.Test, ""); err != nil {
+			return nil, fmt.Errorf("invalid quarantine %s: test %q: %w", path, entry.Test, err)
+		}
+		if entry.expires, err = time.Parse(quarantineDate, entry.Expires); err != nil {
+			return nil, fmt.Errorf("invalid quarantine %s: %s expires %q, expected a date like %s", path, entry.Test, entry.Expires, quarantineDate)
+		}
+	}
+	return quarantine, nil
+}
+
+// Expired reports whether the entry no longer applies at now, the day after
+// its expiry date
+func (e QuarantineEntry) Expired(now time.Time) bool {
+	return !now.Before(e.expires.AddDate(0, 0, 1))
+}
+
+// Active splits the entries into those that still apply at now and those
+// that expired
+func (q Quarantine) Active(now time.Time) (active, expired Quarantine) {
+	active, expired = Quarantine{}, Quarantine{}
+	for _, entry := range q {
+		if entry.Expired(now) {
+			expired = append(expired, entry)
+			continue
+		}
+		active = append(active, entry)
+	}
+	return active, expired
+}
+
+// Matches reports whether the test id is quarantined
+func (q Quarantine) Matches(id string) bool {
+	for _, entry := range q {
+		if matchTestID(entry.Test, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Without drops the quarantined test ids
+func (q Quarantine) Without(ids []string) []string {
+	kept := []string{}
+	for _, id := range ids {
+		if !q.Matches(id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// Quarantined lists the failed and errored tests of the results that are
+// quarantined
+func (q Quarantine) Quarantined(r Results) []string {
+	ids := []string{}
+	for _, id := range append(append([]string{}, r.Failed...), r.Errored...) {
+		if q.Matches(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// matchTestID matches a test id against a quarantine pattern. Ids join the
+// suite, classname and test with /, which the names may contain too, e.g.
+// Go subtests and Java packages, so the pattern is not split into parts.
+func matchTestID(pattern, id string) bool {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*':
+			expr.WriteString(".*")
+		case c == '?':
+			expr.WriteString(".")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := classEnd(pattern, i)
+			if end < 0 {
+				return false
+			}
+			expr.WriteString(pattern[i : end+1])
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	return err == nil && re.MatchString(id)
+}
+
+// classEnd finds the ] closing the character class opened at start, or -1
+func classEnd(pattern string, start int) int {
+	for i := start + 1; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,105 @@
+package reporter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func writeQuarantine(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "quarantine.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadQuarantine(t *testing.T) {
+	path := writeQuarantine(t, `
+- test: single_failure/**/TestRegister
+  owner: "@accounts"
+  expires: 2026-11-30
+  reason: races the mail server
+- test: pytest/tests.test_api/*
+  owner: "@api"
+  expires: "2026-10-01"
+`)
+	quarantine, err := reporter.LoadQuarantine(path)
+	require.NoError(t, err)
+	require.Len(t, quarantine, 2)
+	assert.Equal(t, "@accounts", quarantine[0].Owner)
+	assert.Equal(t, "2026-11-30", quarantine[0].Expires)
+
+	active, expired := quarantine.Active(time.Date(2026, 10, 1, 23, 59, 0, 0, time.UTC))
+	assert.Len(t, active, 2)
+	assert.Empty(t, expired)
+
+	active, expired = quarantine.Active(time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, reporter.Quarantine{quarantine[0]}, active)
+	assert.Equal(t, reporter.Quarantine{quarantine[1]}, expired)
+
+	assert.True(t, quarantine.Matches(failedID))
+	assert.True(t, quarantine.Matches("pytest/tests.test_api/test_login"))
+	assert.False(t, quarantine.Matches("pytest/tests.test_db/test_login"))
+	assert.Equal(t, []string{"a"}, quarantine.Without([]string{"a", failedID}))
+
+	for _, tt := range []struct {
+		content string
+		err     string
+	}{
+		{"- test: a\n  owner: me\n", "entry 1 needs a test, owner and expires"},
+		{"- test: a\n  owner: me\n  expires: soon\n", `a expires "soon", expected a date like 2006-01-02`},
+		{"- test: '[a'\n  owner: me\n  expires: 2026-01-01\n", `test "[a": syntax error in pattern`},
+		{"test: a\n", "cannot unmarshal"},
+	} {
+		_, err := reporter.LoadQuarantine(writeQuarantine(t, tt.content))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
+
+func TestQuarantineMatches(t *testing.T) {
+	for _, tt := range []struct {
+		test string
+		id   string
+		want bool
+	}{
+		{"go/TestX/case_a", "go/TestX/case_a", true},
+		{"go/TestX/*", "go/TestX/case_a", true},
+		{"go/TestX*", "go/TestX/case_a/nested", true},
+		{"go/TestX/case_?", "go/TestX/case_b", true},
+		{"go/TestX/case_[ab]", "go/TestX/case_c", false},
+		{"go/TestY/*", "go/TestX/case_a", false},
+		{"com/example/shop/CartTest/checksOut", "com/example/shop/CartTest/checksOut", true},
+		{"com/example/*/checksOut", "com/example/shop/CartTest/checksOut", true},
+		{"com/example/**/checksOut", "com/example/shop/CartTest/checksOut", true},
+		{"com/example/**/CartTest/checksOut", "com/example/CartTest/checksOut", true},
+		{"com/example/**/checksOut", "com/other/CartTest/checksOut", false},
+		{`e2e/\*`, "e2e/*", true},
+		{`e2e/\*`, "e2e/a", false},
+	} {
+		quarantine := reporter.Quarantine{{Test: tt.test, Owner: "me", Expires: "2099-01-01"}}
+		assert.Equal(t, tt.want, quarantine.Matches(tt.id), "%s %s", tt.test, tt.id)
+	}
+}
+
+func TestQuarantinedExitCode(t *testing.T) {
+	quarantine := reporter.Quarantine{{Test: "s/flaky*", Owner: "me", Expires: "2099-01-01"}}
+	results := reporter.Results{Tests: 3, Failed: []string{"s/flaky1"}, Errored: []string{"s/flaky2"}}
+	policy := reporter.Policy{FailOn: []string{"failures", "new-failures", "errors"}, Baseline: map[string]bool{}, Quarantine: quarantine}
+
+	code, why := policy.ExitCode(results)
+	assert.Equal(t, reporter.ExitOK, code)
+	assert.Empty(t, why)
+	assert.Equal(t, []string{"s/flaky1", "s/flaky2"}, quarantine.Quarantined(results))
+
+	results.Failed = append(results.Failed, "s/other")
+	code, why = policy.ExitCode(results)
+	assert.Equal(t, reporter.ExitFailures, code)
+	assert.Equal(t, "1 tests failed", why)
+}
@@ -0,0 +1,48 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const redacted = "[REDACTED]"
+
+// minTokenLength keeps a placeholder token, as used in tests, from masking
+// every occurrence of a few common characters
+const minTokenLength = 8
+
+// redact masks the upload token, and anything matching the Redact patterns,
+// in the report contents and commit metadata before they leave the machine
+func (r *RequestPayload) redact() error {
+	patterns := []*regexp.Regexp{}
+	if len(r.UploadToken) >= minTokenLength {
+		patterns = append(patterns, regexp.MustCompile(regexp.QuoteMeta(r.UploadToken)))
+	}
+	for _, pattern := range r.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid -redact pattern %q: %w", pattern, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("invalid -redact pattern %q: matches an empty string", pattern)
+		}
+		patterns = append(patterns, re)
+	}
+
+	for _, re := range patterns {
+		for i, data := range r.RequestData.RunData {
+			r.RequestData.RunData[i] = re.ReplaceAll(data, []byte(redacted))
+		}
+		r.RequestData.Commit.Subject = re.ReplaceAllString(r.RequestData.Commit.Subject, redacted)
+		r.RequestData.Describe = re.ReplaceAllString(r.RequestData.Describe, redacted)
+		if r.RequestData.Command != nil {
+			for i, arg := range r.RequestData.Command.Args {
+				r.RequestData.Command.Args[i] = re.ReplaceAllString(arg, redacted)
+			}
+		}
+		for name, value := range r.RequestData.Flags {
+			r.RequestData.Flags[name] = re.ReplaceAllString(value, redacted)
+		}
+	}
+	return nil
+}
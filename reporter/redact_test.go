@@ -0,0 +1,51 @@
+package reporter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestExcludeAndRedact(t *testing.T) {
+	dir := t.TempDir()
+	report := `<testsuite name="secrets"><testcase name="login"><system-out>token=tr_secret_123 password=hunter2</system-out></testcase></testsuite>`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "junit.xml"), []byte(report), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "junit-flaky.xml"), []byte(report), 0644))
+
+	setEnv(t, "TR_UPLOAD_TOKEN", "tr_secret_123")
+	payload := reporter.RequestPayload{
+		Files:   []string{filepath.Join(dir, "junit*.xml")},
+		Exclude: []string{"*-flaky.xml"},
+		Redact:  []string{`password=\w+`},
+		RequestData: reporter.RequestData{
+			Branch: "main",
+			SHA:    "sha1",
+			Flags:  map[string]string{"buildurl": "https://ci.example.com/?token=tr_secret_123"},
+		},
+		Logger: testLogger(),
+	}
+	assert.NoError(t, payload.Setup())
+
+	assert.Equal(t, []string{filepath.Join(dir, "junit.xml")}, payload.RequestData.Filenames)
+	assert.Len(t, payload.RequestData.RunData, 1)
+	assert.Contains(t, string(payload.RequestData.RunData[0]), "token=[REDACTED] [REDACTED]<")
+	assert.NotContains(t, string(payload.RequestData.RunData[0]), "hunter2")
+	assert.Equal(t, "https://ci.example.com/?token=[REDACTED]", payload.RequestData.Flags["buildurl"])
+}
+
+func TestInvalidRedactPattern(t *testing.T) {
+	setEnv(t, "TR_UPLOAD_TOKEN", "tr_secret_123")
+	for _, pattern := range []string{`password=(`, `x*`} {
+		payload := reporter.RequestPayload{
+			Files:       []string{"./fixtures/golang_success.xml"},
+			Redact:      []string{pattern},
+			RequestData: reporter.RequestData{Branch: "main", SHA: "sha1"},
+			Logger:      testLogger(),
+		}
+		assert.ErrorContains(t, payload.Setup(), "invalid -redact pattern", pattern)
+	}
+}
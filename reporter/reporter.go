@@ -6,17 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
-	junit "github.com/joshdk/go-junit"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"github.com/testrecall/reporter/ci"
+	"github.com/testrecall/reporter/config"
 )
 
-var branchCommand = strings.Fields("git log -n 1 --pretty=%D HEAD")
+const defaultTokenEnv = "TR_UPLOAD_TOKEN"
 
 const noTokenMessage = `
 	TR_UPLOAD_TOKEN must be set in the environment,
@@ -24,6 +23,10 @@ const noTokenMessage = `
 	app.testrecall.com/projects/<my-project>/integrations
 `
 
+// ErrNoReports is wrapped by the errors of GetRunData when there are no
+// report files to upload
+var ErrNoReports = errors.New("no reports found")
+
 func noFileMessage(filename string) string {
 	return fmt.Sprintf(`
 		unable to find file to upload results at: %s
@@ -36,7 +39,40 @@ const IdempotencyKeyHeader = "Idempotency-Key"
 type RequestPayload struct {
 	IdempotencyKey string
 	UploadToken    string
-	Filename       string
+
+	// Files are report globs, see SearchReportFilesIn. The default
+	// locations are searched when empty, all of them with AllDefaults.
+	Files       []string
+	AllDefaults bool
+	// Reports are report files to upload as they are named, rather than
+	// searched for with Files, e.g. the files watch found
+	Reports []string
+	// FreshAfter skips report files last modified before it, left over
+	// from earlier runs. Any file is uploaded when zero.
+	FreshAfter time.Time
+
+	// Exclude drops report files matching any of these globs
+	Exclude []string
+	// Redact masks matches of these regular expressions before upload
+	Redact []string
+
+	// TokenEnv is the env var holding the upload token, TR_UPLOAD_TOKEN by
+	// default
+	TokenEnv string
+	// Dir scopes the report search and changed files to a subdirectory of
+	// the repository, for monorepo projects
+	Dir string
+
+	// OmitEmails drops author and committer emails from the upload
+	OmitEmails bool
+	// Describe labels the run with git describe
+	Describe bool
+
+	// ChangedFiles collects the files changed since BaseBranch, up to
+	// MaxChangedFiles
+	ChangedFiles    bool
+	BaseBranch      string
+	MaxChangedFiles int
 
 	RequestData RequestData
 
@@ -56,28 +92,81 @@ type RequestData struct {
 	Branch string `json:"branch"`
 	SHA    string `json:"sha"`
 	Tag    string `json:"tag"`
-	PR     string `json:"pr"`
+
+	Describe string `json:"describe,omitempty"`
+	PR       string `json:"pr"`
 
 	Slug        string `json:"slug"`
+	ProjectPath string `json:"project_path,omitempty"`
 	CIName      string `json:"ci_name"`
 	BuildNumber string `json:"build_number"`
 	BuildURL    string `json:"build_url"`
 	Job         string `json:"job"`
+
+	ShardIndex   string `json:"shard_index"`
+	ShardTotal   string `json:"shard_total"`
+	RetryAttempt string `json:"retry_attempt"`
+
+	Commit  Commit   `json:"commit"`
+	Changes *Changes `json:"changes,omitempty"`
+	// Command is the test command when the reporter ran it with exec
+	Command *CommandRun `json:"command,omitempty"`
+	// Part is set when watch uploads the run in parts
+	Part *RunPart `json:"part,omitempty"`
+	// Labels label each failed test id new, persistent or flaky from the
+	// local -history, and each test that passed on a retry flaky
+	Labels map[string]string `json:"labels,omitempty"`
+
+	Provenance map[string]config.Source `json:"provenance"`
 }
 
-func (r *RequestPayload) Setup() {
-	r.IdempotencyKey = newIdempotencyKey()
+// Setup reads the reports and resolves the metadata of the run, returning
+// an error when the payload cannot be uploaded
+func (r *RequestPayload) Setup() error {
+	// parts of a run keep the key they were given
+	if r.IdempotencyKey == "" {
+		r.IdempotencyKey = newIdempotencyKey()
+	}
 
-	r.GetUploadToken()
-	r.GetHostname()
-	r.GetRunData()
+	if err := r.GetUploadToken(); err != nil {
+		return err
+	}
+	if err := r.GetHostname(); err != nil {
+		return err
+	}
+	if err := r.GetRunData(); err != nil {
+		return err
+	}
 
 	r.GetVendor()
 
-	r.GetSHA()
-	r.GetBranch()
+	if err := r.GetSHA(); err != nil {
+		return err
+	}
+	if err := r.GetBranch(); err != nil {
+		return err
+	}
+	r.GetTag()
+	r.GetDescribe()
+	r.GetCommit()
+	r.GetChanges()
+	r.GetSlug()
 	r.GetBuildNumber()
 	r.GetBuildURL()
+
+	r.GetJob()
+	r.GetShardIndex()
+	r.GetShardTotal()
+	r.GetRetryAttempt()
+
+	// only ever set by flags
+	r.preset("pr", r.RequestData.PR)
+
+	if err := r.redact(); err != nil {
+		return err
+	}
+	r.logProvenance()
+	return nil
 }
 
 func newIdempotencyKey() string {
@@ -94,7 +183,7 @@ func (r RequestPayload) isVendorKnown() bool {
 }
 
 func (r RequestPayload) FailureCount() (int, bool) {
-	run, err := junit.Ingest(r.RequestData.RunData[0])
+	run, _, err := ingest(r.RequestData.RunData[0])
 	if err != nil {
 		r.Logger.Debug(err)
 		return 0, false
@@ -111,105 +200,159 @@ func (r *RequestPayload) GetVendor() {
 	if vendor, found := ci.GetVendor(); found {
 		r.Vendor = vendor
 		r.RequestData.CIName = r.Vendor.GetName()
+		r.SetSource("ci_name", config.Source{Kind: config.SourceEnv, Name: r.Vendor.GetEnv()})
 	}
 }
 
-func (r *RequestPayload) GetUploadToken() {
-	r.UploadToken = os.Getenv("TR_UPLOAD_TOKEN")
+func (r *RequestPayload) GetUploadToken() error {
+	if r.TokenEnv == "" || r.TokenEnv == defaultTokenEnv {
+		r.UploadToken = os.Getenv(defaultTokenEnv)
+		if r.UploadToken == "" {
+			return errors.New(noTokenMessage)
+		}
+		return nil
+	}
+
+	r.UploadToken = os.Getenv(r.TokenEnv)
 	if r.UploadToken == "" {
-		r.Logger.Fatal(noTokenMessage)
+		return fmt.Errorf("%s must be set in the environment to upload %s", r.TokenEnv, r.Dir)
 	}
+	return nil
 }
 
-func (r *RequestPayload) GetBranch() {
-	if r.RequestData.Branch != "" {
-		return
+func (r *RequestPayload) GetBranch() error {
+	if r.preset("branch", r.RequestData.Branch) {
+		return nil
 	}
 
 	if r.isVendorKnown() {
-		r.RequestData.Branch = r.Vendor.GetBranch()
+		r.RequestData.Branch = NormalizeBranch(r.fromVendor("branch", r.Vendor.GetBranch()), nil)
 		if r.RequestData.Branch != "" {
-			return
+			return nil
 		}
 	}
 
-	if _, err := exec.LookPath("git"); err != nil {
-		r.Logger.Fatal("-sha is a required field")
+	branch, source, err := gitBranch(r.Logger)
+	if branch != "" {
+		r.RequestData.Branch = branch
+		r.SetSource("branch", config.Source{Kind: config.SourceGit, Name: source})
+		return nil
 	}
 
-	// NOTE: ci may be in a detached head
-	out, err := exec.Command(branchCommand[0], branchCommand[1:]...).CombinedOutput()
-	r.Logger.Debugln("branch: ", string(out))
-	if err != nil {
-		r.Logger.Fatal("git error checking for detached head ", err)
+	if branch, env := envBranch(); branch != "" {
+		r.RequestData.Branch = branch
+		r.SetSource("branch", config.Source{Kind: config.SourceEnv, Name: env})
+		return nil
 	}
-	rawOut := string(out)
-	r.RequestData.Branch = GitBranchFromInfo(rawOut)
-
-	r.Logger.Debugln(r.RequestData.Branch, rawOut)
+	return err
 }
 
-func GitBranchFromInfo(info string) string {
-	trimmed := strings.TrimSuffix(info, "\n")
+func (r *RequestPayload) GetSHA() error {
+	if r.preset("sha", r.RequestData.SHA) {
+		return nil
+	}
+
+	if r.isVendorKnown() {
+		r.RequestData.SHA = r.fromVendor("sha", r.Vendor.GetSHA())
+		if r.RequestData.SHA != "" {
+			return nil
+		}
+	}
 
-	forward := strings.Split(trimmed, "->")
-	var branch string
-	if len(forward) >= 2 {
-		branches := strings.Split(forward[1], ",")
-		branch = branches[0]
-	} else {
-		branches := strings.Split(trimmed, ",")
-		branch = branches[len(branches)-1]
+	sha, source, err := gitSHA(r.Logger)
+	if err != nil {
+		return err
 	}
-	return strings.TrimSpace(branch)
+	r.RequestData.SHA = sha
+	r.SetSource("sha", config.Source{Kind: config.SourceGit, Name: source})
+	return nil
 }
 
-func (r *RequestPayload) GetSHA() {
-	if r.RequestData.SHA != "" {
+func (r *RequestPayload) GetTag() {
+	if r.preset("tag", r.RequestData.Tag) {
 		return
 	}
 
 	if r.isVendorKnown() {
-		r.RequestData.SHA = r.Vendor.GetSHA()
-		if r.RequestData.SHA != "" {
+		r.RequestData.Tag = r.fromVendor("tag", r.Vendor.GetTag())
+		if r.RequestData.Tag != "" {
 			return
 		}
 	}
 
-	if _, err := exec.LookPath("git"); err != nil {
-		r.Logger.Fatal("-sha is a required field")
+	tag, source, err := gitTag(r.Logger)
+	if err != nil {
+		r.Logger.Debugln("unable to detect tag: ", err)
+		return
+	}
+	r.RequestData.Tag = tag
+	if tag != "" {
+		r.SetSource("tag", config.Source{Kind: config.SourceGit, Name: source})
 	}
+}
 
-	out, err := exec.Command("git", "rev-parse", "HEAD").CombinedOutput()
+func (r *RequestPayload) GetDescribe() {
+	if !r.Describe || r.preset("describe", r.RequestData.Describe) {
+		return
+	}
+
+	out, err := runGit(describeCommand)
 	if err != nil {
-		r.Logger.Fatal("git error using rev-parse", err)
+		r.Logger.Debugln("unable to run git describe: ", err)
+		return
 	}
-	rawOut := string(out)
-	rawOut = strings.TrimSuffix(rawOut, "\n")
-	r.RequestData.SHA = rawOut
+	r.RequestData.Describe = strings.TrimSpace(out)
+	r.SetSource("describe", config.Source{Kind: config.SourceGit, Name: strings.Join(describeCommand, " ")})
 }
 
-func (r *RequestPayload) GetHostname() {
-	if r.RequestData.Hostname != "" {
+// GetSlug falls back from the vendor's repository variables to the origin
+// remote url
+func (r *RequestPayload) GetSlug() {
+	if r.preset("slug", r.RequestData.Slug) {
+		return
+	}
+
+	if r.isVendorKnown() {
+		r.RequestData.Slug = r.fromVendor("slug", r.Vendor.GetSlug())
+		if r.RequestData.Slug != "" {
+			return
+		}
+	}
+
+	slug, source, err := gitSlug(r.Logger)
+	if err != nil {
+		r.Logger.Debugln("unable to detect slug: ", err)
 		return
 	}
+	r.RequestData.Slug = slug
+	if slug != "" {
+		r.SetSource("slug", config.Source{Kind: config.SourceGit, Name: source})
+	}
+}
+
+func (r *RequestPayload) GetHostname() error {
+	if r.preset("hostname", r.RequestData.Hostname) {
+		return nil
+	}
 
 	h, err := os.Hostname()
 	if err != nil {
-		r.Logger.Fatal("unable to detect hostname", err)
+		return fmt.Errorf("unable to detect hostname: %w", err)
 	}
 	r.RequestData.Hostname = h
+	r.SetSource("hostname", config.Source{Kind: config.SourceSystem, Name: "hostname"})
+	return nil
 }
 
 func (r *RequestPayload) GetBuildNumber() {
-	if r.RequestData.BuildNumber != "" {
+	if r.preset("build_number", r.RequestData.BuildNumber) {
 		return
 	}
 
 	r.Logger.Debugf("vendor: %v", r.isVendorKnown())
 	if r.isVendorKnown() {
 		r.Logger.Debugf("vendor build number: %v", r.Vendor.GetBuildNumber())
-		r.RequestData.BuildNumber = r.Vendor.GetBuildNumber()
+		r.RequestData.BuildNumber = r.fromVendor("build_number", r.Vendor.GetBuildNumber())
 		if r.RequestData.BuildNumber != "" {
 			return
 		}
@@ -217,83 +360,129 @@ func (r *RequestPayload) GetBuildNumber() {
 }
 
 func (r *RequestPayload) GetBuildURL() {
-	if r.RequestData.BuildURL != "" {
+	if r.preset("build_url", r.RequestData.BuildURL) {
 		return
 	}
 
 	if r.isVendorKnown() {
-		r.RequestData.BuildURL = r.Vendor.GetBuildURL()
+		r.RequestData.BuildURL = r.fromVendor("build_url", r.Vendor.GetBuildURL())
 		if r.RequestData.BuildURL != "" {
 			return
 		}
 	}
 }
 
-func (r *RequestPayload) GetRunData() {
-	fs := afero.NewOsFs()
-	files, err := SearchReportFiles(fs, r.Filename)
-	if err != nil {
-		r.Logger.Fatal(err)
+func (r *RequestPayload) GetJob() {
+	if r.preset("job", r.RequestData.Job) {
+		return
 	}
-	r.RequestData.Filenames = files
 
-	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			r.Logger.Fatal(err)
-		}
-		r.RequestData.RunData = append(r.RequestData.RunData, data)
+	if r.isVendorKnown() {
+		r.RequestData.Job = r.fromVendor("job", r.Vendor.GetJob())
 	}
+}
 
-	if r.Filename == "" && len(r.RequestData.RunData) == 0 {
-		r.Logger.Fatal("-file is a required field")
+func (r *RequestPayload) GetShardIndex() {
+	if r.preset("shard_index", r.RequestData.ShardIndex) {
+		return
 	}
-}
 
-var defaultPatterns = []string{
-	"./*/*/TEST-*.xml",
-	"./*/*/*/TEST-*.xml",
-	"./*/*/*/*/TEST-*.xml",
-	"./*/*/*/*/*/TEST-*.xml",
+	if r.isVendorKnown() {
+		r.RequestData.ShardIndex = r.fromVendor("shard_index", r.Vendor.GetShardIndex())
+	}
+}
 
-	"junit*.xml",
-	"rspec*.xml",
-	"report*.xml",
+func (r *RequestPayload) GetShardTotal() {
+	if r.preset("shard_total", r.RequestData.ShardTotal) {
+		return
+	}
 
-	"./reports/junit*.xml",
-	"./reports/rspec*.xml",
-	"./reports/report*.xml",
+	if r.isVendorKnown() {
+		r.RequestData.ShardTotal = r.fromVendor("shard_total", r.Vendor.GetShardTotal())
+	}
+}
 
-	"./test-results/junit*.xml",
-	"./test-results/rspec*.xml",
-	"./test-results/report*.xml",
+func (r *RequestPayload) GetRetryAttempt() {
+	if r.preset("retry_attempt", r.RequestData.RetryAttempt) {
+		return
+	}
 
-	"/tmp/test-results/junit*.xml",
-	"/tmp/test-results/rspec*.xml",
-	"/tmp/test-results/report*.xml",
+	if r.isVendorKnown() {
+		r.RequestData.RetryAttempt = r.fromVendor("retry_attempt", r.Vendor.GetRetryAttempt())
+	}
 }
 
-func SearchReportFiles(fs afero.Fs, pattern string) ([]string, error) {
-	// check for suplied pattern
-	if pattern != "" {
-		files, err := afero.Glob(fs, pattern)
-		if err != nil {
-			return []string{}, err
-		}
+// GetRunData reads the report files to upload. It returns an error wrapping
+// ErrNoReports when none were found, or all that were are excluded or stale.
+func (r *RequestPayload) GetRunData() error {
+	// reports given directly, e.g. a crash report
+	if len(r.RequestData.RunData) > 0 {
+		return nil
+	}
 
-		if len(files) == 0 {
-			return []string{}, errors.New(noFileMessage(pattern))
+	// the last part of a watched run may only close it
+	if part := r.RequestData.Part; part != nil && part.Final {
+		if len(r.Reports) == 0 {
+			return nil
 		}
-
-		return files, nil
+		err := r.readRunData()
+		if errors.Is(err, ErrNoReports) {
+			r.Logger.Warn(err)
+			r.RequestData.Filenames = []string{}
+			return nil
+		}
+		return err
 	}
+	return r.readRunData()
+}
 
-	for _, p := range defaultPatterns {
-		if matched, err := afero.Glob(fs, p); err != nil || len(matched) > 0 {
-			return matched, err
+func (r *RequestPayload) readRunData() error {
+	fs := afero.NewOsFs()
+	files := r.Reports
+	if len(files) == 0 {
+		found, skipped, err := searchReportFiles(fs, r.Dir, r.Files, r.AllDefaults)
+		for _, skip := range skipped {
+			if skip.Format == "" {
+				r.Logger.Debug(skip)
+				continue
+			}
+			r.Logger.Warn(skip)
 		}
+		if err != nil {
+			return err
+		}
+		files = found
+	}
+	files = r.excludeFiles(files)
+	if len(files) == 0 {
+		return fmt.Errorf("%w: every report file found is excluded by -exclude", ErrNoReports)
+	}
+	files, stale := r.freshFiles(fs, files)
+	if len(stale) > 0 {
+		r.Logger.Warn(staleMessage(stale, r.FreshAfter))
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%w: every report file found is stale, last modified before %s", ErrNoReports, r.FreshAfter.Format(time.RFC3339))
 	}
 
-	// no files found
-	return []string{}, errors.New(noFileMessage(pattern))
+	read, runData := []string{}, [][]byte{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			// removed since the search, e.g. by a test command still running
+			r.Logger.Warnf("skipping %s, it no longer exists", file)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		read = append(read, file)
+		runData = append(runData, data)
+	}
+	if len(runData) == 0 {
+		return fmt.Errorf("%w: every report file found has since been removed", ErrNoReports)
+	}
+	r.RequestData.Filenames = read
+	r.RequestData.RunData = runData
+	return nil
 }
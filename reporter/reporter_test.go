@@ -11,6 +11,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testrecall/reporter/ci"
+	"github.com/testrecall/reporter/config"
 	"github.com/testrecall/reporter/reporter"
 )
 
@@ -59,8 +62,11 @@ func TestFailureCount(t *testing.T) {
 	}
 }
 
+// fixturesDir is resolved before any test changes the working directory
+var fixturesDir, _ = filepath.Abs("fixtures")
+
 func getFixture(filename string) []byte {
-	fp := filepath.Join("fixtures", filename)
+	fp := filepath.Join(fixturesDir, filename)
 	b, err := os.ReadFile(fp)
 	if err != nil {
 		fmt.Print(err)
@@ -73,7 +79,7 @@ func TestSetup(t *testing.T) {
 	os.Setenv("TR_UPLOAD_TOKEN", "abc123")
 
 	payload := reporter.RequestPayload{
-		Filename: "./fixtures/hello.txt",
+		Files: []string{"./fixtures/hello.txt"},
 		RequestData: reporter.RequestData{
 			Branch:          "branch1",
 			SHA:             "sha1",
@@ -83,11 +89,287 @@ func TestSetup(t *testing.T) {
 		},
 		Logger: testLogger(),
 	}
-	payload.Setup()
+	require.NoError(t, payload.Setup())
 
 	assert.Less(t, 1, len(payload.RequestData.ReporterVersion), payload.RequestData.ReporterVersion)
 }
 
+func TestProvenance(t *testing.T) {
+	clearEnv(t)
+	setEnv(t, "TR_UPLOAD_TOKEN", "abc123")
+	setEnv(t, "GITLAB_CI", "true")
+	setEnv(t, "CI_COMMIT_SHA", "sha123")
+	setEnv(t, "CI_JOB_ID", "7")
+
+	payload := reporter.RequestPayload{
+		Files: []string{"./fixtures/golang_success.xml"},
+		RequestData: reporter.RequestData{
+			Branch: "branch1",
+			Tag:    "v1.0.0",
+		},
+		Logger: testLogger(),
+	}
+	payload.SetSource("branch", config.Source{Kind: config.SourceFlag, Name: "-branch"})
+	require.NoError(t, payload.Setup())
+
+	assert.Equal(t, map[string]config.Source{
+		"branch":       {Kind: config.SourceFlag, Name: "-branch"},
+		"tag":          {Kind: config.SourceFlag},
+		"ci_name":      {Kind: config.SourceEnv, Name: "GITLAB_CI"},
+		"sha":          {Kind: config.SourceEnv, Name: "CI_COMMIT_SHA"},
+		"build_number": {Kind: config.SourceEnv, Name: "CI_JOB_ID"},
+		"hostname":     {Kind: config.SourceSystem, Name: "hostname"},
+	}, payload.RequestData.Provenance)
+}
+
+func TestGetSHAWithoutGit(t *testing.T) {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Skip("not running inside a git checkout")
+	}
+
+	clearEnv(t) // no PATH, so no git binary
+	payload := reporter.RequestPayload{Logger: testLogger()}
+	require.NoError(t, payload.GetSHA())
+
+	assert.Equal(t, strings.TrimSpace(string(out)), payload.RequestData.SHA)
+	assert.Equal(t, config.SourceGit, payload.RequestData.Provenance["sha"].Kind)
+	assert.True(t, strings.HasSuffix(payload.RequestData.Provenance["sha"].Name, "HEAD"))
+}
+
+func TestGetCommit(t *testing.T) {
+	dir, cleanup := newRepo(t)
+	defer cleanup()
+	chdir(t, dir)
+
+	out, err := gitNewBrach(dir, "feature")
+	assert.NoError(t, err, string(out))
+	assert.NoError(t, createBarFile(dir))
+	out, err = gitAdd(dir)
+	assert.NoError(t, err, string(out))
+	out, err = gitCommit(dir, "feature commit")
+	assert.NoError(t, err, string(out))
+	out, err = gitCheckout(dir, "-")
+	assert.NoError(t, err, string(out))
+	out, err = runCmd(dir, "git merge --no-ff --no-edit feature")
+	assert.NoError(t, err, string(out))
+
+	payload := reporter.RequestPayload{Logger: testLogger()}
+	payload.GetCommit()
+
+	commit := payload.RequestData.Commit
+	assert.Equal(t, "testuser", commit.AuthorName)
+	assert.Equal(t, "you@example.com", commit.AuthorEmail)
+	assert.Equal(t, "testuser", commit.CommitterName)
+	assert.Equal(t, "Merge branch 'feature'", commit.Subject)
+	assert.NotEmpty(t, commit.AuthoredAt)
+	assert.Len(t, commit.Parents, 2)
+	assert.True(t, commit.IsMerge())
+
+	t.Setenv("TEST_COMMIT_AUTHOR", "Jane Doe <jane@example.com>")
+	t.Setenv("TEST_COMMIT_MESSAGE", "vendor subject\n\nbody")
+	payload = reporter.RequestPayload{
+		Logger:     testLogger(),
+		OmitEmails: true,
+		Vendor: ci.Vendor{
+			CommitAuthor:  "TEST_COMMIT_AUTHOR",
+			CommitMessage: "TEST_COMMIT_MESSAGE",
+		},
+		RequestData: reporter.RequestData{CIName: "test"},
+	}
+	payload.GetCommit()
+
+	commit = payload.RequestData.Commit
+	assert.Equal(t, "Jane Doe", commit.AuthorName)
+	assert.Equal(t, "", commit.AuthorEmail)
+	assert.Equal(t, "", commit.CommitterEmail)
+	assert.Equal(t, "testuser", commit.CommitterName)
+	assert.Equal(t, "vendor subject", commit.Subject)
+	assert.Equal(t, config.Source{Kind: config.SourceEnv, Name: "TEST_COMMIT_AUTHOR"}, payload.RequestData.Provenance["commit_author"])
+}
+
+func TestGetTag(t *testing.T) {
+	dir, cleanup := newRepo(t)
+	defer cleanup()
+	chdir(t, dir)
+
+	payload := reporter.RequestPayload{Logger: testLogger(), Describe: true}
+	payload.GetTag()
+	payload.GetDescribe()
+	assert.Equal(t, "", payload.RequestData.Tag)
+	assert.Len(t, payload.RequestData.Describe, 7) // abbreviated sha
+
+	for _, tag := range []string{"v1.9.0", "v1.10.0"} {
+		out, err := runCmd(dir, "git tag "+tag)
+		assert.NoError(t, err, string(out))
+	}
+
+	payload = reporter.RequestPayload{Logger: testLogger(), Describe: true}
+	payload.GetTag()
+	payload.GetDescribe()
+	assert.Equal(t, "v1.10.0", payload.RequestData.Tag)
+	assert.Contains(t, []string{"v1.9.0", "v1.10.0"}, payload.RequestData.Describe)
+	assert.Equal(t, config.SourceGit, payload.RequestData.Provenance["tag"].Kind)
+
+	payload = reporter.RequestPayload{
+		Logger:      testLogger(),
+		RequestData: reporter.RequestData{Tag: "v2.0.0"},
+	}
+	payload.GetTag()
+	assert.Equal(t, "v2.0.0", payload.RequestData.Tag)
+	assert.Equal(t, "", payload.RequestData.Describe)
+}
+
+func TestGetSlug(t *testing.T) {
+	dir, cleanup := newRepo(t)
+	defer cleanup()
+	chdir(t, dir)
+	path := os.Getenv("PATH")
+	clearEnv(t)
+	setEnv(t, "PATH", path)
+
+	payload := reporter.RequestPayload{Logger: testLogger()}
+	payload.GetSlug()
+	assert.Equal(t, "", payload.RequestData.Slug)
+
+	out, err := runCmd(dir, "git remote add origin git@gitlab.example.com:group/subgroup/project.git")
+	assert.NoError(t, err, string(out))
+
+	payload = reporter.RequestPayload{Logger: testLogger()}
+	payload.GetSlug()
+	assert.Equal(t, "group/subgroup/project", payload.RequestData.Slug)
+	assert.Equal(t, config.Source{Kind: config.SourceGit, Name: "git remote get-url origin"}, payload.RequestData.Provenance["slug"])
+
+	setEnv(t, "GITHUB_ACTIONS", "true")
+	setEnv(t, "GITHUB_REPOSITORY", "TestRecall/reporter")
+	payload = reporter.RequestPayload{Logger: testLogger()}
+	payload.GetVendor()
+	payload.GetSlug()
+	assert.Equal(t, "TestRecall/reporter", payload.RequestData.Slug)
+	assert.Equal(t, config.Source{Kind: config.SourceEnv, Name: "GITHUB_REPOSITORY"}, payload.RequestData.Provenance["slug"])
+
+	payload = reporter.RequestPayload{
+		Logger:      testLogger(),
+		RequestData: reporter.RequestData{Slug: "flag/slug"},
+	}
+	payload.GetVendor()
+	payload.GetSlug()
+	assert.Equal(t, "flag/slug", payload.RequestData.Slug)
+}
+
+func TestGetBranchDetachedBehindTag(t *testing.T) {
+	dir, cleanup := newRepo(t)
+	defer cleanup()
+	chdir(t, dir)
+
+	out, err := runCmd(dir, "git tag v1.0.0")
+	assert.NoError(t, err, string(out))
+	assert.NoError(t, createBarFile(dir))
+	out, err = gitAdd(dir)
+	assert.NoError(t, err, string(out))
+	out, err = gitCommit(dir, "second commit")
+	assert.NoError(t, err, string(out))
+
+	// HEAD is only decorated by the tag, the branch has moved on
+	out, err = gitCheckout(dir, "v1.0.0")
+	assert.NoError(t, err, string(out))
+	assert.Equal(t, "master", reporterBranch())
+
+	// a commit no branch contains falls back to the environment
+	for _, env := range []string{"GIT_BRANCH", "BRANCH_NAME", "CI_BRANCH"} {
+		t.Setenv(env, "")
+	}
+	// too generic to be the CI branch
+	t.Setenv("BRANCH", "shell-branch")
+	assert.NoError(t, createBuzzFile(dir))
+	out, err = gitAdd(dir)
+	assert.NoError(t, err, string(out))
+	out, err = gitCommit(dir, "detached commit")
+	assert.NoError(t, err, string(out))
+	assert.Equal(t, "", reporterBranch())
+
+	t.Setenv("GIT_BRANCH", "origin/release")
+	assert.Equal(t, "release", reporterBranch())
+}
+
+func TestGetChanges(t *testing.T) {
+	dir, cleanup := newRepo(t)
+	t.Cleanup(cleanup)
+	chdir(t, dir)
+
+	base, err := gitSha(dir)
+	assert.NoError(t, err)
+
+	out, err := gitNewBrach(dir, "feature")
+	assert.NoError(t, err, string(out))
+	for _, create := range []func(string) error{createBarFile, createBuzzFile} {
+		assert.NoError(t, create(dir))
+		out, err = gitAdd(dir)
+		assert.NoError(t, err, string(out))
+		out, err = gitCommit(dir, "feature commit")
+		assert.NoError(t, err, string(out))
+	}
+
+	payload := reporter.RequestPayload{Logger: testLogger(), ChangedFiles: true, BaseBranch: "master"}
+	payload.GetChanges()
+	assert.Equal(t, &reporter.Changes{
+		BaseBranch: "master",
+		MergeBase:  strings.TrimSpace(string(base)),
+		DiffBase:   reporter.DiffBaseMergeBase,
+		Files:      []string{"bar.txt", "buzz.txt"},
+	}, payload.RequestData.Changes)
+
+	payload = reporter.RequestPayload{Logger: testLogger(), ChangedFiles: true, BaseBranch: "master", MaxChangedFiles: 1}
+	payload.GetChanges()
+	assert.Equal(t, []string{"bar.txt"}, payload.RequestData.Changes.Files)
+	assert.True(t, payload.RequestData.Changes.Truncated)
+
+	// without an origin, the first default branch that exists
+	payload = reporter.RequestPayload{Logger: testLogger(), ChangedFiles: true}
+	payload.GetChanges()
+	assert.Equal(t, "master", payload.RequestData.Changes.BaseBranch)
+	assert.Equal(t, config.Source{Kind: config.SourceGit, Name: "git rev-parse --verify -q master"}, payload.RequestData.Provenance["base_branch"])
+
+	// on the base branch itself, diff the last commit
+	payload = reporter.RequestPayload{Logger: testLogger(), ChangedFiles: true, BaseBranch: "feature"}
+	payload.GetChanges()
+	assert.Equal(t, reporter.DiffBaseParent, payload.RequestData.Changes.DiffBase)
+	assert.Equal(t, []string{"buzz.txt"}, payload.RequestData.Changes.Files)
+
+	// git warns on stderr that the name is ambiguous, which is not output
+	out, err = runCmd(dir, "git tag master master")
+	assert.NoError(t, err, string(out))
+	payload = reporter.RequestPayload{Logger: testLogger(), ChangedFiles: true, BaseBranch: "master"}
+	payload.GetChanges()
+	assert.Equal(t, strings.TrimSpace(string(base)), payload.RequestData.Changes.MergeBase)
+	assert.Equal(t, []string{"bar.txt", "buzz.txt"}, payload.RequestData.Changes.Files)
+
+	// a depth 1 clone has neither the merge-base nor the parent
+	shallow := dir + "_shallow"
+	t.Cleanup(func() { os.RemoveAll(shallow) })
+	out, err = runCmd("/tmp", "git clone -q --depth 1 --branch feature file://"+dir+" "+shallow)
+	assert.NoError(t, err, string(out))
+	chdir(t, shallow)
+
+	payload = reporter.RequestPayload{Logger: testLogger(), ChangedFiles: true, BaseBranch: "master"}
+	payload.GetChanges()
+	assert.True(t, payload.RequestData.Changes.Shallow)
+	assert.Equal(t, "", payload.RequestData.Changes.MergeBase)
+	assert.Empty(t, payload.RequestData.Changes.Files)
+
+	payload = reporter.RequestPayload{Logger: testLogger()}
+	payload.GetChanges()
+	assert.Nil(t, payload.RequestData.Changes)
+}
+
+// chdir changes into dir for the rest of the test
+func chdir(t *testing.T, dir string) {
+	cur, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { assert.NoError(t, os.Chdir(cur)) })
+}
+
 func gitConfig(t *testing.T, dir string) {
 	out, err := runCmd(dir, `git config commit.gpgsign false`)
 	assert.NoError(t, err, string(out))
@@ -181,7 +463,9 @@ func TestGetBranch(t *testing.T) {
 func reporterBranch() string {
 	fmt.Println("getting branch")
 	payload := reporter.RequestPayload{Logger: testLogger()}
-	payload.GetBranch()
+	if err := payload.GetBranch(); err != nil {
+		return err.Error()
+	}
 	return payload.RequestData.Branch
 }
 
@@ -0,0 +1,153 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/xml"
+
+	junit "github.com/joshdk/go-junit"
+)
+
+// rerunElements are written by Surefire inside a testcase for each failed
+// attempt before the last one. The testcase only fails if its last attempt
+// failed too.
+var rerunElements = map[string]bool{
+	"flakyFailure": true,
+	"flakyError":   true,
+	"rerunFailure": true,
+	"rerunError":   true,
+}
+
+// ingest parses a junit report, counting a test whose last attempt passed
+// after a failed one as passed on a retry within the run. It returns the ids of those tests,
+// whether the retry is recorded as rerun elements or as a repeated testcase.
+func ingest(data []byte) ([]junit.Suite, []string, error) {
+	suites, err := junit.Ingest(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rerun := map[string]bool{}
+	for _, id := range rerunTests(data) {
+		rerun[id] = true
+	}
+	// the position of the last passed and failed attempt of each test, as
+	// a retry always comes after the attempt it retries
+	order, seen := []string{}, map[string]bool{}
+	lastPassed, lastFailed := map[string]int{}, map[string]int{}
+	attempt := 0
+	eachTest(suites, func(suite junit.Suite, test junit.Test) {
+		id := TestID(suite, test)
+		attempt++
+		switch test.Status {
+		case junit.StatusPassed:
+			lastPassed[id] = attempt
+		case junit.StatusFailed, junit.StatusError:
+			lastFailed[id] = attempt
+		default:
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			order = append(order, id)
+		}
+	})
+
+	ids, flaky := []string{}, map[string]bool{}
+	for _, id := range order {
+		if lastPassed[id] > lastFailed[id] && (lastFailed[id] > 0 || rerun[id]) {
+			ids = append(ids, id)
+			flaky[id] = true
+		}
+	}
+	dropRetries(suites, flaky, map[string]bool{})
+	return suites, ids, nil
+}
+
+// LabelRetries labels the tests that passed on a retry within the run
+// flaky, next to any labels from the history
+func (r *RequestPayload) LabelRetries() {
+	for _, id := range r.Results().Flaky {
+		if r.RequestData.Labels == nil {
+			r.RequestData.Labels = map[string]string{}
+		}
+		r.RequestData.Labels[id] = LabelFlaky
+	}
+}
+
+// rerunTests lists the tests with rerun elements, which go-junit ignores
+func rerunTests(data []byte) []string {
+	ids := []string{}
+	suites := []string{}
+	testcase := ""
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ids
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch {
+			case token.Name.Local == "testsuite":
+				suites = append(suites, attr(token, "name"))
+			case token.Name.Local == "testcase" && len(suites) > 0:
+				suite := junit.Suite{Name: suites[len(suites)-1]}
+				testcase = TestID(suite, junit.Test{Name: attr(token, "name"), Classname: attr(token, "classname")})
+			case rerunElements[token.Name.Local] && testcase != "":
+				ids = append(ids, testcase)
+				testcase = ""
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "testsuite":
+				if len(suites) > 0 {
+					suites = suites[:len(suites)-1]
+				}
+			case "testcase":
+				testcase = ""
+			}
+		}
+	}
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// eachTest calls fn with every test of suites and their nested suites
+func eachTest(suites []junit.Suite, fn func(suite junit.Suite, test junit.Test)) {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			fn(suite, test)
+		}
+		eachTest(suite.Suites, fn)
+	}
+}
+
+// dropRetries keeps a single passed attempt of each flaky test, and totals
+// the suites again
+func dropRetries(suites []junit.Suite, flaky, kept map[string]bool) {
+	for i := range suites {
+		suite := &suites[i]
+		tests := []junit.Test{}
+		for _, test := range suite.Tests {
+			id := TestID(*suite, test)
+			if flaky[id] && (test.Status != junit.StatusPassed || kept[id]) {
+				continue
+			}
+			kept[id] = true
+			tests = append(tests, test)
+		}
+		suite.Tests = tests
+
+		dropRetries(suite.Suites, flaky, kept)
+		suite.Aggregate()
+	}
+}
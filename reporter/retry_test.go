@@ -0,0 +1,70 @@
+package reporter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestRetries(t *testing.T) {
+	for _, tt := range []struct {
+		fixture string
+		tests   int
+		failed  []string
+		flaky   []string
+	}{
+		{
+			"surefire_rerun.xml", 3,
+			[]string{"com.example.CartTest/checksOut"},
+			[]string{"com.example.CartTest/appliesDiscount"},
+		},
+		{
+			"pytest_rerun.xml", 3,
+			[]string{"pytest/tests.test_api/test_profile"},
+			[]string{"pytest/tests.test_api/test_login"},
+		},
+		// a pass before the last failure is another test sharing the id,
+		// not a retry
+		{"repeated_names.xml", 3, []string{"pytest/tests.test_parse/test_date"}, []string{}},
+		{"golang_fail.xml", 3, []string{"single_failure/m/register/register/TestRegister"}, []string{}},
+	} {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload := fixturesPayload(tt.fixture)
+
+			count, ok := payload.FailureCount()
+			assert.True(t, ok)
+			assert.Equal(t, len(tt.failed), count)
+
+			results := payload.Results()
+			assert.Equal(t, tt.tests, results.Tests)
+			assert.Equal(t, tt.failed, results.Failed)
+			assert.Equal(t, tt.flaky, results.Flaky)
+
+			summaries := payload.Summarize()
+			assert.Equal(t, tt.flaky, summaries[0].Retried)
+			assert.Equal(t, tt.tests, summaries[0].Totals.Tests)
+			assert.Equal(t, tt.tests-len(tt.failed), summaries[0].Totals.Passed)
+		})
+	}
+}
+
+func TestLabelRetries(t *testing.T) {
+	payload := fixturesPayload("pytest_rerun.xml")
+	payload.RequestData.Labels = map[string]string{"pytest/tests.test_api/test_profile": reporter.LabelNew}
+	payload.LabelRetries()
+	assert.Equal(t, map[string]string{
+		"pytest/tests.test_api/test_profile": reporter.LabelNew,
+		"pytest/tests.test_api/test_login":   reporter.LabelFlaky,
+	}, payload.RequestData.Labels)
+
+	payload = fixturesPayload("golang_success.xml")
+	payload.LabelRetries()
+	assert.Nil(t, payload.RequestData.Labels)
+
+	out := &bytes.Buffer{}
+	fixturesPayload("surefire_rerun.xml").Summary(out)
+	assert.Contains(t, out.String(), "Flaky, passed on a retry:\n  com.example.CartTest/appliesDiscount\n")
+}
@@ -0,0 +1,239 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+var defaultPatterns = []string{
+	"./*/*/TEST-*.xml",
+	"./*/*/*/TEST-*.xml",
+	"./*/*/*/*/TEST-*.xml",
+	"./*/*/*/*/*/TEST-*.xml",
+
+	"junit*.xml",
+	"rspec*.xml",
+	"report*.xml",
+
+	"./reports/junit*.xml",
+	"./reports/rspec*.xml",
+	"./reports/report*.xml",
+
+	"./test-results/junit*.xml",
+	"./test-results/rspec*.xml",
+	"./test-results/report*.xml",
+
+	"/tmp/test-results/junit*.xml",
+	"/tmp/test-results/rspec*.xml",
+	"/tmp/test-results/report*.xml",
+}
+
+func SearchReportFiles(fs afero.Fs, patterns ...string) ([]string, error) {
+	return SearchReportFilesIn(fs, "", patterns, false)
+}
+
+// SearchReportFilesIn searches relative patterns, and the default patterns,
+// inside dir. ** matches any number of directories, and a pattern starting
+// with ! drops the files it matches. Files matched more than once are only
+// returned once, and files a wildcard matched that are not junit reports are
+// skipped. Without any patterns to include, the default patterns are
+// searched up to the first that matches, or all of them with allDefaults.
+func SearchReportFilesIn(fs afero.Fs, dir string, patterns []string, allDefaults bool) ([]string, error) {
+	files, _, err := searchReportFiles(fs, dir, patterns, allDefaults)
+	return files, err
+}
+
+// searchReportFiles is SearchReportFilesIn, also returning the files that
+// were skipped as not junit reports
+func searchReportFiles(fs afero.Fs, dir string, patterns []string, allDefaults bool) ([]string, []SkippedFile, error) {
+	include, exclude := splitPatterns(patterns)
+
+	searched := dir
+	defaults := len(include) == 0
+	if defaults {
+		include = defaultPatterns
+	} else {
+		searched = strings.Join(include, ", ")
+	}
+
+	files := []string{}
+	skipped := []SkippedFile{}
+	seen := map[string]bool{}
+	for _, p := range include {
+		// shared locations like /tmp are not part of any one project
+		if defaults && dir != "" && filepath.IsAbs(p) {
+			continue
+		}
+
+		matched, err := glob(fs, inDir(dir, p))
+		if err != nil {
+			return []string{}, skipped, err
+		}
+
+		found := false
+		for _, file := range matched {
+			if matchesAny(file, dir, exclude) {
+				continue
+			}
+			key := filepath.Clean(file)
+			if seen[key] {
+				found = true
+				continue
+			}
+			seen[key] = true
+
+			if skip, ok := isSkipped(fs, p, file); ok {
+				skipped = append(skipped, skip)
+				continue
+			}
+			found = true
+			files = append(files, file)
+		}
+		if defaults && found && !allDefaults {
+			break
+		}
+	}
+
+	if len(files) == 0 {
+		return []string{}, skipped, fmt.Errorf("%w:%s", ErrNoReports, noFileMessage(searched))
+	}
+	return files, skipped, nil
+}
+
+// splitPatterns separates the patterns to include from the ! exclusions
+func splitPatterns(patterns []string) (include, exclude []string) {
+	for _, p := range patterns {
+		switch {
+		case p == "":
+		case strings.HasPrefix(p, "!"):
+			exclude = append(exclude, p[1:])
+		default:
+			include = append(include, p)
+		}
+	}
+	return include, exclude
+}
+
+// glob is afero.Glob, with ** matching any number of directories
+func glob(fs afero.Fs, pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return afero.Glob(fs, pattern)
+	}
+
+	pattern = filepath.Clean(pattern)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	matches := []string{}
+	err := afero.Walk(fs, globRoot(pattern), func(path string, info os.FileInfo, err error) error {
+		// unreadable directories are skipped, as afero.Glob does
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && matchPath(pattern, path) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// globRoot is the directory above the first wildcard of a pattern
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, string(filepath.Separator))
+	for i, segment := range segments {
+		if hasMeta(segment) {
+			if i == 1 && segments[0] == "" {
+				return string(filepath.Separator)
+			}
+			if i == 0 {
+				return "."
+			}
+			return strings.Join(segments[:i], string(filepath.Separator))
+		}
+	}
+	return pattern
+}
+
+func hasMeta(segment string) bool {
+	magic := `*?[`
+	if filepath.Separator != '\\' {
+		magic = `*?[\`
+	}
+	return strings.ContainsAny(segment, magic)
+}
+
+// matchPath matches a path against a glob, where ** matches any number of
+// directories
+func matchPath(pattern, name string) bool {
+	return matchSegments(
+		strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/"),
+		strings.Split(filepath.ToSlash(filepath.Clean(name)), "/"),
+	)
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := filepath.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchesAny matches a file by its path, its path inside dir, or its base
+// name
+func matchesAny(file, dir string, patterns []string) bool {
+	names := []string{file, filepath.Base(file)}
+	if rel, err := filepath.Rel(dir, file); dir != "" && err == nil {
+		names = append(names, rel)
+	}
+
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matchPath(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludeFiles drops files matching an exclude glob
+func (r RequestPayload) excludeFiles(files []string) []string {
+	kept := []string{}
+	for _, file := range files {
+		if !r.isExcluded(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+func (r RequestPayload) isExcluded(file string) bool {
+	return matchesAny(file, r.Dir, r.Exclude)
+}
+
+func inDir(dir, pattern string) string {
+	if dir == "" || filepath.IsAbs(pattern) {
+		return pattern
+	}
+	return filepath.Join(dir, pattern)
+}
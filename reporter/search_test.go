@@ -0,0 +1,97 @@
+package reporter_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func reportsFs(t *testing.T, files ...string) afero.Fs {
+	fs := afero.NewMemMapFs()
+	for _, file := range files {
+		require.NoError(t, afero.WriteFile(fs, filepath.FromSlash(file), []byte("<testsuites/>"), 0644))
+	}
+	return fs
+}
+
+func TestSearchReportFilesPatterns(t *testing.T) {
+	fs := reportsFs(t,
+		"/r/junit.xml",
+		"/r/a/TEST-1.xml",
+		"/r/a/b/flaky.xml",
+		"/r/a/b/c/TEST-2.xml",
+		"/r/reports/junit-1.xml",
+		"/r/reports/notes.txt",
+	)
+
+	for _, tt := range []struct {
+		name     string
+		patterns []string
+		files    []string
+	}{
+		{"recursive", []string{"/r/**/*.xml"}, []string{
+			"/r/a/TEST-1.xml", "/r/a/b/c/TEST-2.xml", "/r/a/b/flaky.xml", "/r/junit.xml", "/r/reports/junit-1.xml",
+		}},
+		{"recursive in the middle", []string{"/r/a/**/TEST-*.xml"}, []string{
+			"/r/a/TEST-1.xml", "/r/a/b/c/TEST-2.xml",
+		}},
+		{"repeated and overlapping", []string{"/r/**/TEST-*.xml", "/r/a/*/*/TEST-2.xml", "/r/junit.xml"}, []string{
+			"/r/a/TEST-1.xml", "/r/a/b/c/TEST-2.xml", "/r/junit.xml",
+		}},
+		{"excluded by path", []string{"/r/**/*.xml", "!/r/a/**"}, []string{
+			"/r/junit.xml", "/r/reports/junit-1.xml",
+		}},
+		{"excluded by name", []string{"!flaky.xml", "/r/a/**/*.xml"}, []string{
+			"/r/a/TEST-1.xml", "/r/a/b/c/TEST-2.xml",
+		}},
+		{"empty patterns are ignored", []string{"", "/r/junit.xml"}, []string{
+			"/r/junit.xml",
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := reporter.SearchReportFiles(fs, fromSlash(tt.patterns)...)
+			require.NoError(t, err)
+			assert.Equal(t, fromSlash(tt.files), files)
+		})
+	}
+
+	_, err := reporter.SearchReportFiles(fs, fromSlash([]string{"/r/**/*.json", "/r/missing.xml"})...)
+	assert.Error(t, err)
+
+	_, err = reporter.SearchReportFiles(fs, fromSlash([]string{"/r/**/*.xml", "!**/*.xml"})...)
+	assert.Error(t, err)
+
+	_, err = reporter.SearchReportFiles(fs, "/r/**/[.xml")
+	assert.Error(t, err)
+}
+
+func TestSearchReportFilesDefaults(t *testing.T) {
+	fs := reportsFs(t, "/r/junit.xml", "/r/reports/junit-1.xml")
+	dir := filepath.FromSlash("/r")
+
+	files, err := reporter.SearchReportFilesIn(fs, dir, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, fromSlash([]string{"/r/junit.xml"}), files)
+
+	files, err = reporter.SearchReportFilesIn(fs, dir, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, fromSlash([]string{"/r/junit.xml", "/r/reports/junit-1.xml"}), files)
+
+	// a default pattern left without reports does not stop the search
+	files, err = reporter.SearchReportFilesIn(fs, dir, []string{"!junit.xml"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, fromSlash([]string{"/r/reports/junit-1.xml"}), files)
+}
+
+func fromSlash(paths []string) []string {
+	native := []string{}
+	for _, path := range paths {
+		native = append(native, filepath.FromSlash(path))
+	}
+	return native
+}
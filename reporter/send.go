@@ -56,16 +56,48 @@ func NewSender(logger *logrus.Logger) sender {
 }
 
 func (s sender) Send(remoteURL string, payload RequestPayload) error {
+	_, err := s.SendRun(remoteURL, payload)
+	return err
+}
+
+// StatusError is an upload the server answered with an unexpected status
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Upload status code: %v, body: %v", e.Code, e.Body)
+}
+
+// Rejected reports whether the server refused the upload itself, so sending
+// it again cannot succeed, rather than failing to take it for now
+func (e *StatusError) Rejected() bool {
+	switch e.Code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return e.Code >= 400 && e.Code < 500
+}
+
+// createdRun is the part of an upload response naming the run
+type createdRun struct {
+	URL string `json:"url"`
+}
+
+// SendRun uploads the payload and returns the url of the run, which is ""
+// when TestRecall does not return one
+func (s sender) SendRun(remoteURL string, payload RequestPayload) (string, error) {
 	jsonValue, err := json.Marshal(payload.RequestData)
 	if err != nil {
-		return err
+		return "", err
 	}
 	s.Logger.Debug("outgoing data: ", string(jsonValue))
 
 	url := remoteURL + "/runs"
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
 	if err != nil {
-		return fmt.Errorf("Unable to create upload request: %w", err)
+		return "", fmt.Errorf("Unable to create upload request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Add(IdempotencyKeyHeader, payload.IdempotencyKey)
@@ -74,11 +106,11 @@ func (s sender) Send(remoteURL string, payload RequestPayload) error {
 
 	rreq, err := retryablehttp.FromRequest(req)
 	if err != nil {
-		return fmt.Errorf("Unable to create retryable request: %w", err)
+		return "", fmt.Errorf("Unable to create retryable request: %w", err)
 	}
 	resp, err := s.client.Do(rreq)
 	if err != nil {
-		return fmt.Errorf("Error uploading data: %w", err)
+		return "", fmt.Errorf("Error uploading data: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -86,10 +118,15 @@ func (s sender) Send(remoteURL string, payload RequestPayload) error {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			s.Logger.Errorln(err, resp.StatusCode)
-			return fmt.Errorf("Error decoding response: %v", err)
+			return "", fmt.Errorf("Error decoding response: %v", err)
 		}
-		return fmt.Errorf("Upload status code: %v, body: %v", resp.StatusCode, string(body))
+		return "", &StatusError{Code: resp.StatusCode, Body: string(body)}
 	}
 
-	return err
+	run := createdRun{}
+	if body, err := io.ReadAll(resp.Body); err == nil {
+		// older servers answer with an empty body
+		_ = json.Unmarshal(body, &run)
+	}
+	return run.URL, nil
 }
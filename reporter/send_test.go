@@ -96,3 +96,25 @@ func testingHTTPClient(handler http.Handler) (*httptest.Server, func()) {
 
 	return s, s.Close
 }
+
+func TestSendRun(t *testing.T) {
+	body := `{"id": 12, "url": "https://app.testrecall.com/runs/12"}`
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	})
+
+	s, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	sender := reporter.NewSender(testLogger())
+	url, err := sender.SendRun(s.URL, reporter.RequestPayload{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.testrecall.com/runs/12", url)
+
+	body = ""
+	url, err = sender.SendRun(s.URL, reporter.RequestPayload{})
+	assert.NoError(t, err)
+	assert.Empty(t, url)
+}
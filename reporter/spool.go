@@ -0,0 +1,121 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// spooledPayload is a run that failed to upload, kept for a later run to
+// send. The token is read from TokenEnv again when sending, so it is never
+// written to disk.
+type spooledPayload struct {
+	IdempotencyKey string      `json:"idempotency_key"`
+	TokenEnv       string      `json:"token_env,omitempty"`
+	RequestData    RequestData `json:"request_data"`
+}
+
+// Spool writes a payload that failed to upload into dir, returning the file
+// it was written to
+func Spool(dir string, payload RequestPayload) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	content, err := json.Marshal(spooledPayload{
+		IdempotencyKey: payload.IdempotencyKey,
+		TokenEnv:       payload.TokenEnv,
+		RequestData:    payload.RequestData,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, payload.IdempotencyKey+".json")
+	return path, os.WriteFile(path, content, 0o600)
+}
+
+// rejectedDir keeps the spooled runs the server refused, out of the way of
+// the runs still to send
+const rejectedDir = "rejected"
+
+// SendSpooled uploads the runs spooled in dir by earlier runs, removing each
+// once sent. It returns how many were sent, and stops at the first upload
+// that failed for want of a connection or a working server, so an outage is
+// not retried for every file. A run the server refused is moved into the
+// rejected directory, so it cannot hold up the rest.
+func (s sender) SendSpooled(remoteURL, dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(paths)
+
+	sent := 0
+	for _, path := range paths {
+		payload, err := readSpooled(path)
+		if err != nil {
+			s.Logger.Warn(err)
+			continue
+		}
+		if payload.UploadToken == "" {
+			s.Logger.Warnf("%s is not set, leaving %s spooled", payload.TokenEnv, path)
+			continue
+		}
+
+		if err := s.Send(remoteURL, payload); err != nil {
+			var status *StatusError
+			if !errors.As(err, &status) || !status.Rejected() {
+				return sent, err
+			}
+			rejected, moveErr := reject(dir, path)
+			if moveErr != nil {
+				return sent, moveErr
+			}
+			s.Logger.Warnf("the spooled run %s was refused and moved to %s: %v", path, rejected, err)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return sent, err
+		}
+		s.Logger.Debugf("sent spooled run %s", path)
+		sent++
+	}
+	return sent, nil
+}
+
+// reject moves a spooled run into the rejected directory
+func reject(dir, path string) (string, error) {
+	rejected := filepath.Join(dir, rejectedDir)
+	if err := os.MkdirAll(rejected, 0o755); err != nil {
+		return "", err
+	}
+	target := filepath.Join(rejected, filepath.Base(path))
+	return target, os.Rename(path, target)
+}
+
+func readSpooled(path string) (RequestPayload, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return RequestPayload{}, err
+	}
+
+	spooled := spooledPayload{}
+	if err := json.Unmarshal(content, &spooled); err != nil {
+		return RequestPayload{}, fmt.Errorf("invalid spooled run %s: %w", path, err)
+	}
+
+	tokenEnv := spooled.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = defaultTokenEnv
+	}
+	return RequestPayload{
+		IdempotencyKey: spooled.IdempotencyKey,
+		UploadToken:    os.Getenv(tokenEnv),
+		TokenEnv:       tokenEnv,
+		RequestData:    spooled.RequestData,
+	}, nil
+}
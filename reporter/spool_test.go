@@ -0,0 +1,107 @@
+package reporter_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestSpool(t *testing.T) {
+	clearEnv(t)
+	setEnv(t, "TR_API_UPLOAD_TOKEN", uploadToken)
+	dir := filepath.Join(t.TempDir(), "spool")
+
+	path, err := reporter.Spool(dir, reporter.RequestPayload{
+		IdempotencyKey: "1_abc",
+		UploadToken:    uploadToken,
+		TokenEnv:       "TR_API_UPLOAD_TOKEN",
+		RequestData: reporter.RequestData{
+			Filenames: []string{"report.xml"},
+			RunData:   [][]byte{[]byte("foo")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "1_abc.json"), path)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), uploadToken)
+
+	// a run without its token is left for later
+	_, err = reporter.Spool(dir, reporter.RequestPayload{IdempotencyKey: "2_def"})
+	require.NoError(t, err)
+
+	status := http.StatusServiceUnavailable
+	received := []reporter.RequestData{}
+	keys := []string{}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		assert.Equal(t, uploadToken, password)
+		keys = append(keys, r.Header.Get(reporter.IdempotencyKeyHeader))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data := reporter.RequestData{}
+		assert.NoError(t, json.Unmarshal(body, &data))
+		received = append(received, data)
+
+		w.WriteHeader(status)
+	})
+	s, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	sender := reporter.NewSender(testLogger())
+	sent, err := sender.SendSpooled(s.URL, dir)
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+	assert.FileExists(t, path)
+	assert.Equal(t, []string{"1_abc", "1_abc", "1_abc", "1_abc", "1_abc"}, keys)
+
+	status = http.StatusCreated
+	sent, err = sender.SendSpooled(s.URL, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, filepath.Join(dir, "2_def.json"))
+
+	last := received[len(received)-1]
+	assert.Equal(t, []string{"report.xml"}, last.Filenames)
+	assert.Equal(t, [][]byte{[]byte("foo")}, last.RunData)
+
+	// a run the server refuses is set aside, and the next is still sent
+	for _, key := range []string{"3_ghi", "4_jkl"} {
+		_, err = reporter.Spool(dir, reporter.RequestPayload{IdempotencyKey: key, TokenEnv: "TR_API_UPLOAD_TOKEN"})
+		require.NoError(t, err)
+	}
+	keys = []string{}
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(reporter.IdempotencyKeyHeader))
+		if r.Header.Get(reporter.IdempotencyKeyHeader) == "3_ghi" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	s2, teardown2 := testingHTTPClient(h)
+	defer teardown2()
+
+	sent, err = sender.SendSpooled(s2.URL, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"3_ghi", "4_jkl"}, keys)
+	assert.NoFileExists(t, filepath.Join(dir, "3_ghi.json"))
+	assert.FileExists(t, filepath.Join(dir, "rejected", "3_ghi.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "4_jkl.json"))
+
+	sent, err = sender.SendSpooled(s.URL, filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
@@ -0,0 +1,162 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	junit "github.com/joshdk/go-junit"
+)
+
+// ReportSummary is the parsed result of one report file
+type ReportSummary struct {
+	Filename string
+	Suites   []junit.Suite
+	Totals   junit.Totals
+	// Retried are the ids of tests that failed and then passed on a retry
+	// within the run, counted as passed in Suites and Totals
+	Retried []string
+	// Err is set when the file is not a report that can be uploaded
+	Err error
+}
+
+// Summarize parses every report file found by GetRunData
+func (r RequestPayload) Summarize() []ReportSummary {
+	summaries := []ReportSummary{}
+	for i, data := range r.RequestData.RunData {
+		summary := ReportSummary{Filename: r.RequestData.Filenames[i]}
+
+		summary.Suites, summary.Retried, summary.Err = ingest(data)
+		if summary.Err == nil && len(summary.Suites) == 0 {
+			summary.Err = errors.New("no test suites found")
+		}
+		for _, suite := range summary.Suites {
+			addTotals(&summary.Totals, suite.Totals)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// Validate prints whether each report file can be parsed, and reports
+// whether all of them can
+func (r RequestPayload) Validate(out io.Writer) bool {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	valid := true
+	for _, summary := range r.Summarize() {
+		if summary.Err != nil {
+			valid = false
+			fmt.Fprintf(w, "%s\tinvalid: %v\n", summary.Filename, summary.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\tok\t%d suites, %d tests\n", summary.Filename, len(summary.Suites), summary.Totals.Tests)
+	}
+	return valid
+}
+
+// Summary prints the test totals of each report file and lists the tests
+// that failed, the tests that only passed on a retry and the files that
+// could not be parsed
+func (r RequestPayload) Summary(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	total := junit.Totals{}
+	failed, retried, invalid := []string{}, []string{}, []string{}
+	fmt.Fprintln(w, "file\ttests\tpassed\tfailed\terrors\tskipped\ttime")
+	for _, summary := range r.Summarize() {
+		if summary.Err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", summary.Filename, summary.Err))
+			continue
+		}
+		printTotals(w, summary.Filename, summary.Totals)
+		addTotals(&total, summary.Totals)
+		failed = append(failed, failedTests(summary.Suites)...)
+		retried = append(retried, summary.Retried...)
+	}
+	printTotals(w, "total", total)
+	w.Flush()
+
+	if len(invalid) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Invalid:")
+		for _, file := range invalid {
+			fmt.Fprintf(out, "  %s\n", file)
+		}
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Failed:")
+		for _, name := range failed {
+			if label := r.RequestData.Labels[name]; label != "" {
+				fmt.Fprintf(out, "  %s (%s)\n", name, label)
+				continue
+			}
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+	}
+
+	if len(retried) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Flaky, passed on a retry:")
+		for _, name := range retried {
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+	}
+}
+
+func printTotals(w io.Writer, name string, t junit.Totals) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%v\n", name, t.Tests, t.Passed, t.Failed, t.Error, t.Skipped, t.Duration)
+}
+
+func addTotals(sum *junit.Totals, t junit.Totals) {
+	sum.Tests += t.Tests
+	sum.Passed += t.Passed
+	sum.Skipped += t.Skipped
+	sum.Failed += t.Failed
+	sum.Error += t.Error
+	sum.Duration += t.Duration
+}
+
+// failedTests names the failed and errored tests of suites
+func failedTests(suites []junit.Suite) []string {
+	return testIDs(suites, junit.StatusFailed, junit.StatusError)
+}
+
+// testIDs names the tests of suites and their nested suites with any of the
+// statuses, as suite/classname/test
+func testIDs(suites []junit.Suite, statuses ...junit.Status) []string {
+	ids := []string{}
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if !hasStatus(test, statuses) {
+				continue
+			}
+			ids = append(ids, TestID(suite, test))
+		}
+		ids = append(ids, testIDs(suite.Suites, statuses...)...)
+	}
+	return ids
+}
+
+// TestID identifies a test as suite/classname/test, leaving out the
+// classname when it repeats the suite name
+func TestID(suite junit.Suite, test junit.Test) string {
+	name := test.Name
+	if test.Classname != "" && test.Classname != suite.Name {
+		name = test.Classname + "/" + name
+	}
+	return suite.Name + "/" + name
+}
+
+func hasStatus(test junit.Test, statuses []junit.Status) bool {
+	for _, status := range statuses {
+		if test.Status == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package reporter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func fixturesPayload(filenames ...string) reporter.RequestPayload {
+	payload := reporter.RequestPayload{Logger: testLogger()}
+	for _, filename := range filenames {
+		payload.RequestData.Filenames = append(payload.RequestData.Filenames, filename)
+		payload.RequestData.RunData = append(payload.RequestData.RunData, getFixture(filename))
+	}
+	return payload
+}
+
+func TestSummarize(t *testing.T) {
+	summaries := fixturesPayload("golang_fail.xml", "rspec_success.xml", "hello.txt", "rspec_malformed.xml").Summarize()
+	assert.Len(t, summaries, 4)
+
+	assert.NoError(t, summaries[0].Err)
+	assert.Len(t, summaries[0].Suites, 2)
+	assert.Equal(t, 3, summaries[0].Totals.Tests)
+	assert.Equal(t, 1, summaries[0].Totals.Failed)
+
+	assert.NoError(t, summaries[1].Err)
+	assert.Equal(t, 2, summaries[1].Totals.Passed)
+
+	assert.EqualError(t, summaries[2].Err, "no test suites found")
+	assert.Error(t, summaries[3].Err)
+}
+
+func TestValidate(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.True(t, fixturesPayload("golang_success.xml", "rspec_success.xml").Validate(out))
+	assert.Equal(t, `golang_success.xml  ok  2 suites, 3 tests
+rspec_success.xml   ok  1 suites, 2 tests
+`, out.String())
+
+	out.Reset()
+	assert.False(t, fixturesPayload("golang_success.xml", "hello.txt").Validate(out))
+	assert.Contains(t, out.String(), "hello.txt           invalid: no test suites found")
+}
+
+func TestSummary(t *testing.T) {
+	out := &bytes.Buffer{}
+	fixturesPayload("golang_fail.xml", "golang_success.xml", "hello.txt").Summary(out)
+	assert.Equal(t, `file                tests  passed  failed  errors  skipped  time
+golang_fail.xml     3      2       1       0       0        30ms
+golang_success.xml  3      3       0       0       0        30ms
+total               6      5       1       0       0        60ms
+
+Invalid:
+  hello.txt: no test suites found
+
+Failed:
+  single_failure/m/register/register/TestRegister
+`, out.String())
+}
@@ -0,0 +1,198 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	junit "github.com/joshdk/go-junit"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// DefaultWatchInterval is how often watch scans for reports, and how long a
+// report must go unchanged to count as complete when the platform cannot
+// tell when it was closed
+const DefaultWatchInterval = 2 * time.Second
+
+// watchPatterns select the reports inside a watched directory by default
+var watchPatterns = []string{"**/*.xml"}
+
+// RunPart marks one of the uploads watch splits a run into. Every part of a
+// run shares its RunID, and the last one is Final.
+type RunPart struct {
+	RunID string `json:"run_id"`
+	Index int    `json:"index"`
+	Final bool   `json:"final"`
+}
+
+// NewRunID returns an ID for a run uploaded in parts
+func NewRunID() string {
+	return newIdempotencyKey()
+}
+
+// SetPart makes the payload one part of a run. The idempotency key is
+// derived from the part, so a retried part is only counted once.
+func (r *RequestPayload) SetPart(runID string, index int, final bool) {
+	r.IdempotencyKey = fmt.Sprintf("%s_%d", runID, index)
+	r.RequestData.Part = &RunPart{RunID: runID, Index: index, Final: final}
+}
+
+// Watcher finds report files in a directory as they are completed, so a
+// long test run can be uploaded as it goes
+type Watcher struct {
+	Dir string
+	// Patterns select the reports relative to Dir, as -file does, every
+	// xml file when empty
+	Patterns []string
+	Interval time.Duration
+	Logger   *logrus.Logger
+
+	fs    afero.Fs
+	files map[string]*watchedFile
+}
+
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	// closed is set when the writer is known to have closed the file
+	closed bool
+	// done is set once the file was uploaded or found not to be a report
+	done bool
+	// existing is set for files there before watching started, until they
+	// change
+	existing bool
+}
+
+func NewWatcher(dir string, patterns []string, interval time.Duration, logger *logrus.Logger) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &Watcher{
+		Dir:      dir,
+		Patterns: patterns,
+		Interval: interval,
+		Logger:   logger,
+		fs:       afero.NewOsFs(),
+		files:    map[string]*watchedFile{},
+	}
+}
+
+// Watch calls found with the reports completed since its last call until
+// stop is closed, then a last time with final set and every report left
+// over, complete or not. Reports already in Dir when watching starts are
+// only uploaded if they change.
+func (w *Watcher) Watch(stop <-chan struct{}, found func(files []string, final bool)) {
+	for _, file := range w.candidates() {
+		if info, err := w.fs.Stat(file); err == nil {
+			w.files[file] = &watchedFile{size: info.Size(), modTime: info.ModTime(), existing: true}
+			w.Logger.Debugf("ignoring %s until it changes, it was there before watching started", file)
+		}
+	}
+
+	closed := w.notify(stop)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			found(w.scan(true), true)
+			return
+		case file := <-closed:
+			if state, known := w.files[file]; known {
+				state.closed = true
+			} else {
+				w.files[file] = &watchedFile{closed: true}
+			}
+		case <-ticker.C:
+		}
+
+		if files := w.scan(false); len(files) > 0 {
+			found(files, false)
+		}
+	}
+}
+
+// candidates are the files in Dir matching the patterns
+func (w *Watcher) candidates() []string {
+	include, exclude := splitPatterns(w.Patterns)
+	if len(include) == 0 {
+		include = watchPatterns
+	}
+
+	files := []string{}
+	seen := map[string]bool{}
+	for _, p := range include {
+		matched, err := glob(w.fs, inDir(w.Dir, p))
+		if err != nil {
+			w.Logger.Debug(err)
+			continue
+		}
+		for _, file := range matched {
+			if !seen[file] && !matchesAny(file, w.Dir, exclude) {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// scan returns the reports completed since the last scan. A report is
+// complete once it parses, and was either closed by its writer or left
+// unchanged for an interval. With final, every junit report not yet
+// returned is.
+func (w *Watcher) scan(final bool) []string {
+	ready := []string{}
+	for _, file := range w.candidates() {
+		info, err := w.fs.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		state, known := w.files[file]
+		if !known {
+			state = &watchedFile{}
+			w.files[file] = state
+		}
+		changed := info.Size() != state.size || !info.ModTime().Equal(state.modTime)
+		state.size, state.modTime = info.Size(), info.ModTime()
+
+		if state.existing {
+			if !changed {
+				continue
+			}
+			state.existing = false
+		}
+		if state.done {
+			if changed {
+				w.Logger.Warnf("%s changed after it was uploaded, the change is not uploaded", file)
+			}
+			continue
+		}
+		if changed && !state.closed && !final {
+			continue
+		}
+
+		content, err := afero.ReadFile(w.fs, file)
+		if err != nil {
+			continue
+		}
+		if format := DetectFormat(content); format != FormatJUnit {
+			if len(content) > 0 || final {
+				state.done = true
+				w.Logger.Debug(SkippedFile{File: file, Format: format})
+			}
+			continue
+		}
+		if _, err := junit.Ingest(content); err != nil && !final {
+			// still being written
+			state.closed = false
+			continue
+		}
+
+		state.done = true
+		ready = append(ready, file)
+	}
+	return ready
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	inotifyDirMask  = unix.IN_CREATE | unix.IN_MOVED_TO | unix.IN_CLOSE_WRITE | unix.IN_ONLYDIR
+	inotifyReadSize = 64 * 1024
+)
+
+// notify streams the files closed after writing, or moved, under Dir with
+// inotify, until stop is closed. It returns nil when inotify is unavailable,
+// leaving Watch to poll.
+func (w *Watcher) notify(stop <-chan struct{}) <-chan string {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		w.Logger.Debugf("inotify is unavailable, polling %s: %v", w.Dir, err)
+		return nil
+	}
+	// non-blocking, so closing the file interrupts a read
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	dirs := map[int]string{}
+	if err := addWatches(fd, w.Dir, dirs); err != nil {
+		w.Logger.Debugf("unable to watch %s, polling it: %v", w.Dir, err)
+		file.Close()
+		return nil
+	}
+
+	go func() {
+		<-stop
+		file.Close()
+	}()
+
+	closed := make(chan string)
+	go func() {
+		buf := make([]byte, inotifyReadSize)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + unix.SizeofInotifyEvent
+				name := strings.TrimRight(string(buf[start:start+int(event.Len)]), "\x00")
+				offset = start + int(event.Len)
+
+				path := filepath.Join(dirs[int(event.Wd)], name)
+				if event.Mask&unix.IN_ISDIR != 0 {
+					if err := addWatches(fd, path, dirs); err != nil {
+						w.Logger.Debugf("unable to watch %s: %v", path, err)
+					}
+					continue
+				}
+				if event.Mask&(unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO) == 0 {
+					continue
+				}
+
+				select {
+				case closed <- path:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return closed
+}
+
+// addWatches watches root and every directory under it
+func addWatches(fd int, root string, dirs map[int]string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		wd, err := unix.InotifyAddWatch(fd, path, inotifyDirMask)
+		if err != nil {
+			return err
+		}
+		dirs[wd] = path
+		return nil
+	})
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package reporter
+
+// notify is only implemented with inotify, other platforms poll
+func (w *Watcher) notify(stop <-chan struct{}) <-chan string {
+	return nil
+}
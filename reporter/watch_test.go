@@ -0,0 +1,99 @@
+package reporter_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("existing.xml", string(getFixture("golang_success.xml")))
+
+	var mu sync.Mutex
+	parts := [][]string{}
+	finals := []bool{}
+	uploaded := func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]string{}, parts...)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	watcher := reporter.NewWatcher(dir, nil, 20*time.Millisecond, testLogger())
+	go func() {
+		defer close(done)
+		watcher.Watch(stop, func(files []string, final bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			parts = append(parts, files)
+			finals = append(finals, final)
+		})
+	}()
+	// let the watcher note the files already there
+	time.Sleep(50 * time.Millisecond)
+
+	write("a.xml", string(getFixture("golang_fail.xml")))
+	write("pom.xml", `<project><modelVersion>4.0.0</modelVersion></project>`)
+	assert.Eventually(t, func() bool { return len(uploaded()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, [][]string{{filepath.Join(dir, "a.xml")}}, uploaded())
+
+	write("sub/b.xml", `<testsuite name="b"><testcase name="still running">`)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, uploaded(), 1)
+
+	close(stop)
+	<-done
+	assert.Equal(t, [][]string{
+		{filepath.Join(dir, "a.xml")},
+		{filepath.Join(dir, "sub", "b.xml")},
+	}, uploaded())
+	assert.Equal(t, []bool{false, true}, finals)
+}
+
+func TestSetPart(t *testing.T) {
+	payload := reporter.RequestPayload{}
+	payload.SetPart("run1", 2, true)
+	assert.Equal(t, "run1_2", payload.IdempotencyKey)
+	assert.Equal(t, &reporter.RunPart{RunID: "run1", Index: 2, Final: true}, payload.RequestData.Part)
+	assert.NotEqual(t, reporter.NewRunID(), reporter.NewRunID())
+}
+
+func TestPartRunData(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "a.xml")
+	require.NoError(t, os.WriteFile(report, getFixture("golang_success.xml"), 0644))
+	// read as named, not as a glob matching b.xml
+	literal := filepath.Join(dir, "[b].xml")
+	require.NoError(t, os.WriteFile(literal, getFixture("golang_fail.xml"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.xml"), getFixture("golang_fail.xml"), 0644))
+	vanished := filepath.Join(dir, "vanished.xml")
+
+	payload := reporter.RequestPayload{Files: []string{"*.xml"}, Reports: []string{report, literal, vanished}, Logger: testLogger()}
+	payload.SetPart("run1", 0, false)
+	require.NoError(t, payload.GetRunData())
+	assert.Equal(t, []string{report, literal}, payload.RequestData.Filenames)
+
+	payload = reporter.RequestPayload{Reports: []string{vanished}, Logger: testLogger()}
+	payload.SetPart("run1", 1, false)
+	assert.ErrorIs(t, payload.GetRunData(), reporter.ErrNoReports)
+
+	// the last part is still sent to close the run
+	payload.SetPart("run1", 1, true)
+	require.NoError(t, payload.GetRunData())
+	assert.Empty(t, payload.RequestData.Filenames)
+	assert.Empty(t, payload.RequestData.RunData)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+// runWatch uploads reports from a directory as they are completed, as parts
+// of one run. The run is finalized when the reporter is signalled, or when
+// the test command given after -- exits.
+func runWatch(o *options, args []string) {
+	dir, command, ok := watchArgs(args)
+	if !ok {
+		o.flags.Usage()
+		os.Exit(2)
+	}
+	if err := validExitPolicy(o.exitPolicy); err != nil {
+		o.fatal(err)
+	}
+
+	policy, err := o.policy()
+	if err != nil {
+		o.fatal(err)
+	}
+	if _, err := o.freshAfter(); err != nil {
+		o.fatal(err)
+	}
+
+	runID := reporter.NewRunID()
+	results := reporter.Results{}
+	parts := 0
+	sent := []sentRun{}
+	var run *reporter.CommandRun
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	watcher := reporter.NewWatcher(dir, o.files, o.interval, o.logger)
+	go func() {
+		defer close(done)
+		watcher.Watch(stop, func(files []string, final bool) {
+			o.logger.Debugf("uploading part %d of run %s: %v", parts, runID, files)
+			payload, err := o.payload()
+			if err != nil {
+				o.logger.Error(err)
+				results.SetupFailed = true
+				return
+			}
+			payload.Reports = files
+			payload.RequestData.Filenames = files
+			if final {
+				payload.RequestData.Command = run
+			}
+			payload.SetPart(runID, parts, final)
+			parts++
+
+			partResults, runs := o.upload([]reporter.RequestPayload{payload})
+			results.Add(partResults)
+			sent = append(sent, runs...)
+		})
+	}()
+
+	if len(command) == 0 {
+		o.logger.Infof("watching %s for reports, stop with Ctrl-C to finish the run", dir)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		sig := <-signals
+		signal.Stop(signals)
+		o.logger.Debugf("received %v, finishing run %s", sig, runID)
+
+		close(stop)
+		<-done
+		o.writeWatchSummary(sent)
+		os.Exit(o.exitCode(policy, results))
+	}
+
+	commandRun := reporter.RunCommand(command, 0, o.logger)
+	o.logger.Debugf("%s exited with %d after %dms", command[0], commandRun.ExitCode, commandRun.DurationMS)
+	run = &commandRun
+
+	close(stop)
+	<-done
+	o.writeWatchSummary(sent)
+	os.Exit(exitStatus(o.exitPolicy, run.ExitCode, o.exitCode(policy, results)))
+}
+
+// watchArgs splits the arguments of watch into the directory to watch and
+// the test command given after --, if any
+func watchArgs(args []string) (dir string, command []string, ok bool) {
+	switch {
+	case len(args) == 1:
+		return args[0], []string{}, true
+	case len(args) > 2 && args[1] == "--":
+		return args[0], args[2:], true
+	}
+	return "", nil, false
+}
+
+// writeWatchSummary writes one job summary for the reports of every part,
+// as they were uploaded, linking to the run of the last part sent
+func (o *options) writeWatchSummary(runs []sentRun) {
+	if len(runs) == 0 {
+		return
+	}
+	summary := runs[0].payload
+	summary.RequestData.Filenames = []string{}
+	summary.RequestData.RunData = [][]byte{}
+	summary.RequestData.Labels = map[string]string{}
+	url := ""
+	for _, run := range runs {
+		summary.RequestData.Filenames = append(summary.RequestData.Filenames, run.payload.RequestData.Filenames...)
+		summary.RequestData.RunData = append(summary.RequestData.RunData, run.payload.RequestData.RunData...)
+		for id, label := range run.payload.RequestData.Labels {
+			summary.RequestData.Labels[id] = label
+		}
+		if run.url != "" {
+			url = run.url
+		}
+	}
+	if len(summary.RequestData.RunData) == 0 {
+		return
+	}
+	o.writeJobSummary(summary, url)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrecall/reporter/reporter"
+)
+
+func TestWatchArgs(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		dir     string
+		command []string
+		ok      bool
+	}{
+		{args: []string{}},
+		{args: []string{"out"}, dir: "out", command: []string{}, ok: true},
+		{args: []string{"out", "--"}},
+		{args: []string{"out", "make"}},
+		{args: []string{"out", "make", "test"}},
+		{args: []string{"out", "--", "make"}, dir: "out", command: []string{"make"}, ok: true},
+		{args: []string{"out", "--", "go", "test", "--", "-v"}, dir: "out", command: []string{"go", "test", "--", "-v"}, ok: true},
+	} {
+		dir, command, ok := watchArgs(tt.args)
+		assert.Equal(t, tt.ok, ok, tt.args)
+		assert.Equal(t, tt.dir, dir, tt.args)
+		assert.Equal(t, tt.command, command, tt.args)
+	}
+}
+
+func TestWriteWatchSummary(t *testing.T) {
+	o := &options{jobSummary: filepath.Join(t.TempDir(), "summary.md"), logger: logrus.New()}
+	part := func(report, label string) reporter.RequestPayload {
+		payload := reporter.RequestPayload{Logger: o.logger}
+		payload.RequestData.Filenames = []string{report}
+		payload.RequestData.RunData = [][]byte{[]byte(`<testsuite name="suite"><testcase name="` + report + `"><failure message="boom"/></testcase></testsuite>`)}
+		payload.RequestData.Labels = map[string]string{"suite/" + report: label}
+		return payload
+	}
+
+	o.writeWatchSummary(nil)
+	assert.NoFileExists(t, o.jobSummary)
+
+	o.writeWatchSummary([]sentRun{
+		{payload: part("first", reporter.LabelNew), url: "https://example.com/run/1"},
+		{payload: part("second", reporter.LabelFlaky)},
+		{payload: reporter.RequestPayload{Logger: o.logger}, url: "https://example.com/run/2"},
+	})
+	content, err := os.ReadFile(o.jobSummary)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "| 2 | 0 | 2 | 0 | 0 |")
+	assert.Contains(t, string(content), "`suite/first` (new)")
+	assert.Contains(t, string(content), "`suite/second` (flaky)")
+	assert.Contains(t, string(content), "(https://example.com/run/2)")
+}